@@ -0,0 +1,200 @@
+package acl
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"QQBot/internal/common"
+	"QQBot/internal/handler"
+	"QQBot/internal/storage"
+)
+
+const aclDataName = "acl" // 黑白名单的存储名称
+
+// GroupLists 单个群的用户黑白名单
+type GroupLists struct {
+	DenyUsers  []int64 `json:"deny_users"`
+	AllowUsers []int64 `json:"allow_users"` // 非空时本群只响应名单内的用户
+}
+
+// Lists 全局黑白名单（持久化）
+type Lists struct {
+	DenyUsers    []int64               `json:"deny_users"`
+	DenyGroups   []int64               `json:"deny_groups"`
+	AllowGroups  []int64               `json:"allow_groups"` // 非空时只在名单内的群响应
+	Groups       map[int64]*GroupLists `json:"groups"`
+	RecordDenied bool                  `json:"record_denied"` // 拒绝响应时是否仍把消息记入群聊上下文
+}
+
+var (
+	aclMu    sync.RWMutex
+	lists    *Lists
+	loadOnce sync.Once // 首次使用时加载（可能在读锁下并发触发）
+)
+
+func init() {
+	handler.Use(Middleware())
+}
+
+// Middleware 拦截被拒绝的消息，放在所有处理器之前
+func Middleware() handler.Middleware {
+	return func(next handler.Next) handler.Next {
+		return func(ctx context.Context, event common.QQEvent) handler.Result {
+			if !Allowed(event) {
+				log.Printf("[名单] trace=%s 拒绝响应: 群:%d 用户:%d", handler.TraceID(ctx), event.GroupID, event.UserID)
+				return handler.Stop
+			}
+			return next(ctx, event)
+		}
+	}
+}
+
+// Allowed 判断是否响应该消息（主人始终可以）
+func Allowed(event common.QQEvent) bool {
+	if common.MasterQQNumber > 0 && event.UserID == common.MasterQQNumber {
+		return true
+	}
+
+	aclMu.RLock()
+	defer aclMu.RUnlock()
+	l := getLocked()
+
+	if contains(l.DenyUsers, event.UserID) {
+		return false
+	}
+	if event.GroupID == 0 {
+		return true
+	}
+	if contains(l.DenyGroups, event.GroupID) {
+		return false
+	}
+	if len(l.AllowGroups) > 0 && !contains(l.AllowGroups, event.GroupID) {
+		return false
+	}
+	if g, ok := l.Groups[event.GroupID]; ok {
+		if contains(g.DenyUsers, event.UserID) {
+			return false
+		}
+		if len(g.AllowUsers) > 0 && !contains(g.AllowUsers, event.UserID) {
+			return false
+		}
+	}
+	return true
+}
+
+// ShouldRecord 判断群消息是否记入群聊上下文（被拒绝的消息按 RecordDenied 决定）
+func ShouldRecord(event common.QQEvent) bool {
+	if Allowed(event) {
+		return true
+	}
+	aclMu.RLock()
+	defer aclMu.RUnlock()
+	return getLocked().RecordDenied
+}
+
+// Snapshot 返回当前名单的副本
+func Snapshot() Lists {
+	aclMu.RLock()
+	defer aclMu.RUnlock()
+	l := getLocked()
+
+	copied := Lists{
+		DenyUsers:    append([]int64(nil), l.DenyUsers...),
+		DenyGroups:   append([]int64(nil), l.DenyGroups...),
+		AllowGroups:  append([]int64(nil), l.AllowGroups...),
+		Groups:       make(map[int64]*GroupLists, len(l.Groups)),
+		RecordDenied: l.RecordDenied,
+	}
+	for groupID, g := range l.Groups {
+		copied.Groups[groupID] = &GroupLists{
+			DenyUsers:  append([]int64(nil), g.DenyUsers...),
+			AllowUsers: append([]int64(nil), g.AllowUsers...),
+		}
+	}
+	return copied
+}
+
+// Update 修改名单并保存
+func Update(fn func(l *Lists)) {
+	aclMu.Lock()
+	defer aclMu.Unlock()
+	l := getLocked()
+	fn(l)
+	for groupID, g := range l.Groups {
+		if len(g.DenyUsers) == 0 && len(g.AllowUsers) == 0 {
+			delete(l.Groups, groupID)
+		}
+	}
+	saveLocked()
+}
+
+// Group 获取（必要时创建）某个群的名单，需在 Update 回调中使用
+func (l *Lists) Group(groupID int64) *GroupLists {
+	g, ok := l.Groups[groupID]
+	if !ok {
+		g = &GroupLists{}
+		l.Groups[groupID] = g
+	}
+	return g
+}
+
+// Add 向名单添加一项，已存在时返回 false
+func Add(list *[]int64, id int64) bool {
+	if contains(*list, id) {
+		return false
+	}
+	*list = append(*list, id)
+	return true
+}
+
+// Remove 从名单移除一项，不存在时返回 false
+func Remove(list *[]int64, id int64) bool {
+	for i, item := range *list {
+		if item == id {
+			*list = append((*list)[:i], (*list)[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func contains(list []int64, id int64) bool {
+	for _, item := range list {
+		if item == id {
+			return true
+		}
+	}
+	return false
+}
+
+// getLocked 获取名单（首次使用时加载），调用方需持有锁
+func getLocked() *Lists {
+	loadOnce.Do(func() {
+		lists = loadFromFile()
+		if lists == nil {
+			lists = &Lists{RecordDenied: true}
+		}
+		if lists.Groups == nil {
+			lists.Groups = make(map[int64]*GroupLists)
+		}
+	})
+	return lists
+}
+
+// saveLocked 保存名单
+func saveLocked() {
+	if err := storage.SaveData(aclDataName, lists); err != nil {
+		log.Printf("[名单] 保存失败: %v", err)
+	}
+}
+
+// loadFromFile 加载名单
+func loadFromFile() *Lists {
+	var l Lists
+	if !storage.LoadData(aclDataName, &l) {
+		// 不存在是正常的
+		return nil
+	}
+	return &l
+}
@@ -0,0 +1,189 @@
+package acl
+
+import (
+	"fmt"
+	"strings"
+
+	"QQBot/internal/command"
+)
+
+func init() {
+	userArgs := []command.Arg{{Name: "QQ", Type: command.ArgQQ}, {Name: "群号", Type: command.ArgQQ, Optional: true}}
+	groupArgs := []command.Arg{{Name: "群号", Type: command.ArgQQ, Optional: true}}
+
+	command.Register(&command.Command{
+		Name:        "acl",
+		Aliases:     []string{"名单"},
+		Description: "管理黑白名单",
+		Permission:  command.PermMaster,
+		Subcommands: []*command.Command{
+			{Name: "show", Aliases: []string{"查看"}, Description: "查看当前名单", Run: runShow},
+			{
+				Name: "deny", Aliases: []string{"拉黑"}, Description: "拉黑用户（指定群号时仅在该群）或群",
+				Subcommands: []*command.Command{
+					{Name: "user", Aliases: []string{"用户"}, Args: userArgs, Run: runUserList(true, true)},
+					{Name: "group", Aliases: []string{"群"}, Args: groupArgs, Run: runGroupList(true, true)},
+				},
+			},
+			{
+				Name: "undeny", Aliases: []string{"解除拉黑"}, Description: "解除拉黑",
+				Subcommands: []*command.Command{
+					{Name: "user", Aliases: []string{"用户"}, Args: userArgs, Run: runUserList(true, false)},
+					{Name: "group", Aliases: []string{"群"}, Args: groupArgs, Run: runGroupList(true, false)},
+				},
+			},
+			{
+				Name: "allow", Aliases: []string{"白名单"}, Description: "加入白名单：群白名单非空时只在名单内的群响应，群内用户白名单非空时只响应名单内的用户",
+				Subcommands: []*command.Command{
+					{Name: "user", Aliases: []string{"用户"}, Args: userArgs, Run: runUserList(false, true)},
+					{Name: "group", Aliases: []string{"群"}, Args: groupArgs, Run: runGroupList(false, true)},
+				},
+			},
+			{
+				Name: "unallow", Aliases: []string{"移出白名单"}, Description: "移出白名单",
+				Subcommands: []*command.Command{
+					{Name: "user", Aliases: []string{"用户"}, Args: userArgs, Run: runUserList(false, false)},
+					{Name: "group", Aliases: []string{"群"}, Args: groupArgs, Run: runGroupList(false, false)},
+				},
+			},
+			{
+				Name: "record", Aliases: []string{"记录"}, Description: "被拒绝的消息是否仍记入群聊上下文",
+				Args: []command.Arg{{Name: "on|off"}}, Run: runRecord,
+			},
+		},
+	})
+}
+
+// runUserList 修改用户黑/白名单：指定群号时修改该群的名单，否则修改全局名单（全局只有黑名单）
+func runUserList(deny bool, add bool) func(ctx *command.Context) error {
+	return func(ctx *command.Context) error {
+		userID := ctx.QQ("QQ")
+		groupID := ctx.QQ("群号")
+		if !deny && groupID == 0 {
+			groupID = ctx.Event.GroupID
+		}
+		if !deny && groupID == 0 {
+			return command.Usagef("用户白名单需要指定群号")
+		}
+
+		changed := false
+		Update(func(l *Lists) {
+			list := &l.DenyUsers
+			if groupID != 0 {
+				if deny {
+					list = &l.Group(groupID).DenyUsers
+				} else {
+					list = &l.Group(groupID).AllowUsers
+				}
+			}
+			changed = modify(list, userID, add)
+		})
+
+		scope := "全局"
+		if groupID != 0 {
+			scope = fmt.Sprintf("群 %d ", groupID)
+		}
+		ctx.Reply(resultText(changed, fmt.Sprintf("%s%s：%d", scope, listName(deny), userID), add))
+		return nil
+	}
+}
+
+// runGroupList 修改群黑/白名单（未指定群号时为当前群）
+func runGroupList(deny bool, add bool) func(ctx *command.Context) error {
+	return func(ctx *command.Context) error {
+		groupID := ctx.Event.GroupID
+		if ctx.Has("群号") {
+			groupID = ctx.QQ("群号")
+		}
+		if groupID == 0 {
+			return command.Usagef("私聊中需要指定群号")
+		}
+
+		changed := false
+		Update(func(l *Lists) {
+			if deny {
+				changed = modify(&l.DenyGroups, groupID, add)
+			} else {
+				changed = modify(&l.AllowGroups, groupID, add)
+			}
+		})
+		ctx.Reply(resultText(changed, fmt.Sprintf("群%s：%d", listName(deny), groupID), add))
+		return nil
+	}
+}
+
+func runRecord(ctx *command.Context) error {
+	var record bool
+	switch strings.ToLower(ctx.String("on|off")) {
+	case "on", "开":
+		record = true
+	case "off", "关":
+		record = false
+	default:
+		return command.Usagef("请输入 on 或 off")
+	}
+
+	Update(func(l *Lists) { l.RecordDenied = record })
+	if record {
+		ctx.Reply("被拒绝的消息仍会记入群聊上下文")
+	} else {
+		ctx.Reply("被拒绝的消息不再记入群聊上下文")
+	}
+	return nil
+}
+
+func runShow(ctx *command.Context) error {
+	l := Snapshot()
+	var sb strings.Builder
+	sb.WriteString("全局黑名单用户：" + formatIDs(l.DenyUsers))
+	sb.WriteString("\n黑名单群：" + formatIDs(l.DenyGroups))
+	sb.WriteString("\n白名单群：" + formatIDs(l.AllowGroups))
+	for groupID, g := range l.Groups {
+		sb.WriteString(fmt.Sprintf("\n群 %d：黑名单 %s，白名单 %s", groupID, formatIDs(g.DenyUsers), formatIDs(g.AllowUsers)))
+	}
+	if l.RecordDenied {
+		sb.WriteString("\n被拒绝的消息：仍记入上下文")
+	} else {
+		sb.WriteString("\n被拒绝的消息：不记入上下文")
+	}
+	ctx.Reply(sb.String())
+	return nil
+}
+
+func modify(list *[]int64, id int64, add bool) bool {
+	if add {
+		return Add(list, id)
+	}
+	return Remove(list, id)
+}
+
+func listName(deny bool) string {
+	if deny {
+		return "黑名单"
+	}
+	return "白名单"
+}
+
+func resultText(changed bool, target string, add bool) string {
+	switch {
+	case changed && add:
+		return "已加入" + target
+	case changed:
+		return "已移出" + target
+	case add:
+		return "已经在" + target + "中了"
+	default:
+		return "本来就不在" + target + "中"
+	}
+}
+
+func formatIDs(ids []int64) string {
+	if len(ids) == 0 {
+		return "（无）"
+	}
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = fmt.Sprintf("%d", id)
+	}
+	return strings.Join(parts, "、")
+}
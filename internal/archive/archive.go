@@ -0,0 +1,177 @@
+package archive
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"QQBot/internal/common"
+	"QQBot/internal/storage"
+)
+
+const dateLayout = "2006-01-02" // 归档文件按天切分，文件名为日期
+
+// ArchiveDataDir 群消息归档目录：每个群一个子目录，每天一个 jsonl 文件（group_{群号}/YYYY-MM-DD.jsonl）
+// 与 AI 上下文窗口（GroupContext）相互独立，保存完整消息，供搜索、总结、统计等功能读取
+var ArchiveDataDir = filepath.Join(storage.HistoryDataDir, "archive")
+
+// Segment 消息段（与 OneBot array 格式一致，如 text、at、image、face）
+type Segment struct {
+	Type string                 `json:"type"`
+	Data map[string]interface{} `json:"data,omitempty"`
+}
+
+// Message 归档的一条群消息
+type Message struct {
+	MessageID  int64     `json:"message_id"`
+	GroupID    int64     `json:"group_id"`
+	UserID     int64     `json:"user_id"`
+	Nickname   string    `json:"nickname,omitempty"`
+	Time       time.Time `json:"time"`
+	Content    string    `json:"content"` // 解析后的文本（@用户名 文本内容）
+	Segments   []Segment `json:"segments,omitempty"`
+	Recalled   bool      `json:"recalled,omitempty"`    // 是否已被撤回
+	RecalledBy int64     `json:"recalled_by,omitempty"` // 撤回操作者（本人或管理员）
+}
+
+// entry 归档文件中的一行：消息本身，或对之前某条消息的撤回标记
+// 文件只追加不修改，读取时再把撤回标记合并到对应消息上
+type entry struct {
+	Event string `json:"event,omitempty"` // 空为消息，"recall" 为撤回
+	Message
+}
+
+var (
+	archiveMu     sync.Mutex
+	lastPruneDate string // 最近一次清理过期归档的日期，每天最多清理一次
+)
+
+// Append 追加一条群消息到归档
+func Append(msg Message) {
+	if msg.GroupID == 0 {
+		return
+	}
+	if msg.Time.IsZero() {
+		msg.Time = time.Now()
+	}
+	write(entry{Message: msg}, msg.Time)
+}
+
+// MarkRecalled 记录群消息被撤回，原消息保留在归档中并带上撤回标记
+func MarkRecalled(groupID, messageID, operatorID int64) {
+	if groupID == 0 || messageID == 0 {
+		return
+	}
+	now := time.Now()
+	write(entry{Event: "recall", Message: Message{
+		MessageID:  messageID,
+		GroupID:    groupID,
+		Time:       now,
+		Recalled:   true,
+		RecalledBy: operatorID,
+	}}, now)
+}
+
+// write 将一行追加到对应群、对应日期的归档文件
+func write(e entry, t time.Time) {
+	line, err := json.Marshal(e)
+	if err != nil {
+		log.Printf("[消息归档] 序列化失败: %v", err)
+		return
+	}
+	date := t.Format(dateLayout)
+
+	archiveMu.Lock()
+	defer archiveMu.Unlock()
+
+	dir := groupDir(e.GroupID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		log.Printf("[消息归档] 创建目录失败: %v", err)
+		return
+	}
+
+	f, err := os.OpenFile(filepath.Join(dir, date+".jsonl"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		log.Printf("[消息归档] 打开归档文件失败: %v", err)
+		return
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		log.Printf("[消息归档] 写入失败: %v", err)
+	}
+
+	// 日期变化时在后台清理过期归档
+	today := time.Now().Format(dateLayout)
+	if lastPruneDate != today {
+		lastPruneDate = today
+		go Prune()
+	}
+}
+
+// Prune 删除超出保留天数的归档文件，ArchiveRetentionDays 为 0 时永久保留
+func Prune() {
+	if common.ArchiveRetentionDays <= 0 {
+		return
+	}
+	cutoff := time.Now().AddDate(0, 0, -common.ArchiveRetentionDays).Format(dateLayout)
+
+	archiveMu.Lock()
+	defer archiveMu.Unlock()
+
+	groups, err := os.ReadDir(ArchiveDataDir)
+	if err != nil {
+		return
+	}
+	removed := 0
+	for _, g := range groups {
+		if !g.IsDir() {
+			continue
+		}
+		dir := filepath.Join(ArchiveDataDir, g.Name())
+		files, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		remaining := len(files)
+		for _, f := range files {
+			date, ok := fileDate(f.Name())
+			if !ok || date >= cutoff {
+				continue
+			}
+			if err := os.Remove(filepath.Join(dir, f.Name())); err != nil {
+				log.Printf("[消息归档] 删除过期归档失败: %v", err)
+				continue
+			}
+			removed++
+			remaining--
+		}
+		if remaining == 0 {
+			os.Remove(dir)
+		}
+	}
+	if removed > 0 {
+		log.Printf("[消息归档] 已删除 %d 个超过 %d 天的归档文件", removed, common.ArchiveRetentionDays)
+	}
+}
+
+// groupDir 群归档目录
+func groupDir(groupID int64) string {
+	return filepath.Join(ArchiveDataDir, fmt.Sprintf("group_%d", groupID))
+}
+
+// fileDate 从归档文件名（YYYY-MM-DD.jsonl）中取出日期
+func fileDate(name string) (string, bool) {
+	if filepath.Ext(name) != ".jsonl" {
+		return "", false
+	}
+	date := name[:len(name)-len(".jsonl")]
+	if _, err := time.Parse(dateLayout, date); err != nil {
+		return "", false
+	}
+	return date, true
+}
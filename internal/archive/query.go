@@ -0,0 +1,92 @@
+package archive
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// maxLineSize 单行归档的最大长度（含图片等消息段时一行可能较长）
+const maxLineSize = 1 << 20
+
+// Query 读取群在 [since, until) 时间范围内的归档消息，按时间顺序返回
+// 已撤回的消息也会返回（Recalled 为 true），由调用方决定是否展示
+func Query(groupID int64, since, until time.Time) []Message {
+	if until.IsZero() {
+		until = time.Now()
+	}
+	if !since.Before(until) {
+		return nil
+	}
+
+	var messages []Message
+	recalls := make(map[int64]int64) // 消息 ID -> 撤回操作者
+
+	// 撤回可能发生在消息之后的某一天，因此撤回标记读到 until 当天为止
+	for day := startOfDay(since); day.Before(until); day = day.AddDate(0, 0, 1) {
+		readDay(groupID, day, func(e entry) {
+			if e.Event == "recall" {
+				recalls[e.MessageID] = e.RecalledBy
+				return
+			}
+			if e.Time.Before(since) || !e.Time.Before(until) {
+				return
+			}
+			messages = append(messages, e.Message)
+		})
+	}
+
+	for i := range messages {
+		if operator, ok := recalls[messages[i].MessageID]; ok && messages[i].MessageID != 0 {
+			messages[i].Recalled = true
+			messages[i].RecalledBy = operator
+		}
+	}
+	return messages
+}
+
+// Recent 读取群最近 n 条未撤回的消息，最多向前查找 days 天
+func Recent(groupID int64, n int, days int) []Message {
+	if n <= 0 || days <= 0 {
+		return nil
+	}
+	now := time.Now()
+	var result []Message
+	for _, msg := range Query(groupID, startOfDay(now).AddDate(0, 0, 1-days), now.Add(time.Second)) {
+		if !msg.Recalled {
+			result = append(result, msg)
+		}
+	}
+	if len(result) > n {
+		result = result[len(result)-n:]
+	}
+	return result
+}
+
+// readDay 逐行读取某天的归档文件，无法解析的行会被跳过（如写入中途崩溃留下的半行）
+func readDay(groupID int64, day time.Time, fn func(entry)) {
+	f, err := os.Open(filepath.Join(groupDir(groupID), day.Format(dateLayout)+".jsonl"))
+	if err != nil {
+		// 当天没有消息是正常的
+		return
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	for scanner.Scan() {
+		var e entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+		fn(e)
+	}
+}
+
+// startOfDay 当天 0 点（本地时间）
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
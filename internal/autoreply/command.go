@@ -0,0 +1,174 @@
+package autoreply
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"QQBot/internal/command"
+)
+
+const listReplyMaxRune = 30 // 列出规则时回复最多显示的字符数
+
+// 匹配方式和可修改字段的中文别名
+var (
+	matchAliases = map[string]string{
+		"exact": MatchExact, "精确": MatchExact, "完全": MatchExact,
+		"contains": MatchContains, "包含": MatchContains,
+		"regex": MatchRegex, "正则": MatchRegex,
+	}
+	matchNames = map[string]string{
+		MatchExact:    "精确",
+		MatchContains: "包含",
+		MatchRegex:    "正则",
+	}
+	fieldAliases = map[string]string{
+		"priority": "priority", "优先级": "priority",
+		"cooldown": "cooldown", "冷却": "cooldown",
+		"probability": "probability", "概率": "probability",
+		"reply": "reply", "回复": "reply",
+	}
+)
+
+func init() {
+	command.Register(&command.Command{
+		Name:        "autoreply",
+		Aliases:     []string{"自动回复", "关键词"},
+		Description: "管理本群的关键词自动回复",
+		Permission:  command.PermAdmin,
+		GroupOnly:   true,
+		Subcommands: []*command.Command{
+			{
+				Name: "add", Aliases: []string{"添加"},
+				Description: "添加规则，类型为 精确/包含/正则；回复可用 {nickname} {at} {qq} {time} {date} {weekday} {1}~{9}",
+				Args: []command.Arg{
+					{Name: "类型"},
+					{Name: "关键词"},
+					{Name: "回复", Type: command.ArgRest},
+				},
+				Run: runAdd,
+			},
+			{Name: "list", Aliases: []string{"列表"}, Description: "查看本群的规则", Run: runList},
+			{Name: "remove", Aliases: []string{"删除"}, Description: "删除规则", Args: []command.Arg{{Name: "编号", Type: command.ArgInt}}, Run: runRemove},
+			{
+				Name: "set", Aliases: []string{"设置"},
+				Description: "修改规则的 优先级（越小越先匹配）/冷却（秒）/概率（0~1 或百分比）/回复",
+				Args: []command.Arg{
+					{Name: "编号", Type: command.ArgInt},
+					{Name: "字段"},
+					{Name: "值", Type: command.ArgRest},
+				},
+				Run: runSet,
+			},
+		},
+	})
+}
+
+func runAdd(ctx *command.Context) error {
+	match, ok := matchAliases[ctx.String("类型")]
+	if !ok {
+		return command.Usagef("类型应为 精确、包含 或 正则")
+	}
+	r, err := AddRule(ctx.Event.GroupID, Rule{
+		Match:   match,
+		Pattern: ctx.String("关键词"),
+		Reply:   ctx.String("回复"),
+		Creator: ctx.Event.UserID,
+	})
+	if err != nil {
+		return command.Usagef("%v", err)
+	}
+	ctx.Reply(fmt.Sprintf("已添加自动回复 #%d（%s匹配「%s」）", r.ID, matchNames[r.Match], r.Pattern))
+	return nil
+}
+
+func runList(ctx *command.Context) error {
+	list := Rules(ctx.Event.GroupID)
+	if len(list) == 0 {
+		ctx.Reply("本群还没有自动回复规则")
+		return nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("自动回复规则（%d 条，按匹配顺序）：", len(list)))
+	for _, r := range list {
+		sb.WriteString(fmt.Sprintf("\n#%d [%s]「%s」→ %s", r.ID, matchNames[r.Match], r.Pattern, truncateRunes(r.Reply, listReplyMaxRune)))
+		var opts []string
+		if r.Priority != 0 {
+			opts = append(opts, fmt.Sprintf("优先级 %d", r.Priority))
+		}
+		if r.CooldownSec > 0 {
+			opts = append(opts, fmt.Sprintf("冷却 %d 秒", r.CooldownSec))
+		}
+		if r.Probability < 1 {
+			opts = append(opts, fmt.Sprintf("概率 %.0f%%", r.Probability*100))
+		}
+		if len(opts) > 0 {
+			sb.WriteString("\n    " + strings.Join(opts, "，"))
+		}
+	}
+	ctx.Reply(sb.String())
+	return nil
+}
+
+func runRemove(ctx *command.Context) error {
+	id := ctx.Int("编号")
+	if !RemoveRule(ctx.Event.GroupID, id) {
+		ctx.Reply(fmt.Sprintf("没有编号为 %d 的规则", id))
+		return nil
+	}
+	ctx.Reply(fmt.Sprintf("已删除自动回复 #%d", id))
+	return nil
+}
+
+func runSet(ctx *command.Context) error {
+	id := ctx.Int("编号")
+	field, ok := fieldAliases[ctx.String("字段")]
+	if !ok {
+		return command.Usagef("字段应为 优先级、冷却、概率 或 回复")
+	}
+	value := ctx.String("值")
+
+	var update func(r *Rule)
+	switch field {
+	case "priority":
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return command.Usagef("优先级应为整数")
+		}
+		update = func(r *Rule) { r.Priority = n }
+	case "cooldown":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return command.Usagef("冷却应为不小于 0 的秒数")
+		}
+		update = func(r *Rule) { r.CooldownSec = n }
+	case "probability":
+		p, err := command.ParseProbability(value)
+		if err != nil {
+			return err
+		}
+		if p == 0 {
+			return command.Usagef("概率应大于 0（不想触发可以删除规则）")
+		}
+		update = func(r *Rule) { r.Probability = p }
+	case "reply":
+		update = func(r *Rule) { r.Reply = value }
+	}
+
+	if !UpdateRule(ctx.Event.GroupID, id, update) {
+		ctx.Reply(fmt.Sprintf("没有编号为 %d 的规则", id))
+		return nil
+	}
+	ctx.Reply(fmt.Sprintf("已修改自动回复 #%d", id))
+	return nil
+}
+
+// truncateRunes 按字符截断
+func truncateRunes(s string, max int) string {
+	r := []rune(s)
+	if len(r) <= max {
+		return s
+	}
+	return string(r[:max]) + "…"
+}
@@ -0,0 +1,31 @@
+package autoreply
+
+import (
+	"context"
+
+	"QQBot/internal/common"
+	"QQBot/internal/handler"
+)
+
+func init() {
+	handler.Register(autoReplyHandler{})
+}
+
+// autoReplyHandler 按群配置的关键词规则自动回复（在 AI 对话之前，不消耗 API 调用）
+type autoReplyHandler struct{}
+
+func (autoReplyHandler) Name() string  { return "autoreply" }
+func (autoReplyHandler) Priority() int { return 35 }
+
+func (autoReplyHandler) Match(event common.QQEvent) bool {
+	return event.MsgType == "group" && event.UserID != common.BotQQNumber && hasMatch(event.GroupID, event.Content)
+}
+
+func (autoReplyHandler) Handle(_ context.Context, event common.QQEvent) handler.Result {
+	reply, ok := fire(event)
+	if !ok {
+		return handler.Continue // 没通过概率判定，交给后面的处理器
+	}
+	common.SendReply(event, reply)
+	return handler.Stop
+}
@@ -0,0 +1,288 @@
+package autoreply
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"QQBot/internal/common"
+	"QQBot/internal/storage"
+)
+
+const rulesDataName = "auto_reply" // 关键词自动回复规则的存储名称
+
+// 匹配方式
+const (
+	MatchExact    = "exact"    // 整条消息等于关键词
+	MatchContains = "contains" // 消息包含关键词
+	MatchRegex    = "regex"    // 正则表达式
+)
+
+// Rule 一条自动回复规则
+type Rule struct {
+	ID          int     `json:"id"`
+	Match       string  `json:"match"`
+	Pattern     string  `json:"pattern"`
+	Reply       string  `json:"reply"`
+	Priority    int     `json:"priority"`     // 数值越小越先匹配
+	CooldownSec int     `json:"cooldown_sec"` // 触发后多少秒内不再触发，0 表示不限
+	Probability float64 `json:"probability"`  // 触发概率（0~1]
+	Creator     int64   `json:"creator"`
+
+	re *regexp.Regexp
+}
+
+// groupRules 一个群的规则
+type groupRules struct {
+	NextID int     `json:"next_id"`
+	Rules  []*Rule `json:"rules"`
+}
+
+var (
+	rulesMu   sync.Mutex
+	rules     map[int64]*groupRules // 群号 -> 规则
+	lastFired = make(map[string]time.Time)
+)
+
+// AddRule 为群添加规则，返回带编号的规则副本
+func AddRule(groupID int64, r Rule) (Rule, error) {
+	if err := r.compile(); err != nil {
+		return Rule{}, err
+	}
+	if r.Probability <= 0 || r.Probability > 1 {
+		r.Probability = 1
+	}
+
+	rulesMu.Lock()
+	defer rulesMu.Unlock()
+	loadRulesLocked()
+
+	g := rules[groupID]
+	if g == nil {
+		g = &groupRules{}
+		rules[groupID] = g
+	}
+	g.NextID++
+	r.ID = g.NextID
+	g.Rules = append(g.Rules, &r)
+	sortRules(g.Rules)
+	saveRulesLocked()
+
+	log.Printf("[自动回复] 群%d 添加 #%d: %s %q", groupID, r.ID, r.Match, r.Pattern)
+	return r, nil
+}
+
+// RemoveRule 删除群的规则，返回是否存在
+func RemoveRule(groupID int64, id int) bool {
+	rulesMu.Lock()
+	defer rulesMu.Unlock()
+	loadRulesLocked()
+
+	g := rules[groupID]
+	if g == nil {
+		return false
+	}
+	for i, r := range g.Rules {
+		if r.ID == id {
+			g.Rules = append(g.Rules[:i], g.Rules[i+1:]...)
+			saveRulesLocked()
+			log.Printf("[自动回复] 群%d 删除 #%d", groupID, id)
+			return true
+		}
+	}
+	return false
+}
+
+// UpdateRule 修改群的规则（优先级、冷却、概率等），返回是否存在
+func UpdateRule(groupID int64, id int, update func(r *Rule)) bool {
+	rulesMu.Lock()
+	defer rulesMu.Unlock()
+	loadRulesLocked()
+
+	g := rules[groupID]
+	if g == nil {
+		return false
+	}
+	for _, r := range g.Rules {
+		if r.ID == id {
+			update(r)
+			sortRules(g.Rules)
+			saveRulesLocked()
+			return true
+		}
+	}
+	return false
+}
+
+// Rules 按匹配顺序返回群的所有规则副本
+func Rules(groupID int64) []Rule {
+	rulesMu.Lock()
+	defer rulesMu.Unlock()
+	loadRulesLocked()
+
+	var list []Rule
+	if g := rules[groupID]; g != nil {
+		for _, r := range g.Rules {
+			list = append(list, *r)
+		}
+	}
+	return list
+}
+
+// hasMatch 判断消息是否命中群里任一不在冷却中的规则
+func hasMatch(groupID int64, content string) bool {
+	rulesMu.Lock()
+	defer rulesMu.Unlock()
+	loadRulesLocked()
+
+	g := rules[groupID]
+	if g == nil {
+		return false
+	}
+	now := time.Now()
+	for _, r := range g.Rules {
+		if _, ok := r.matches(content); ok && !r.coolingDown(groupID, now) {
+			return true
+		}
+	}
+	return false
+}
+
+// fire 按优先级找到第一条命中、不在冷却中且通过概率判定的规则，返回渲染后的回复
+func fire(event common.QQEvent) (string, bool) {
+	rulesMu.Lock()
+	defer rulesMu.Unlock()
+	loadRulesLocked()
+
+	g := rules[event.GroupID]
+	if g == nil {
+		return "", false
+	}
+	now := time.Now()
+	for _, r := range g.Rules {
+		groups, ok := r.matches(event.Content)
+		if !ok || r.coolingDown(event.GroupID, now) {
+			continue
+		}
+		if r.Probability < 1 && rand.Float64() >= r.Probability {
+			continue
+		}
+		lastFired[cooldownKey(event.GroupID, r.ID)] = now
+		log.Printf("[自动回复] 群%d 用户%d 命中 #%d", event.GroupID, event.UserID, r.ID)
+		return render(r.Reply, event, groups, now), true
+	}
+	return "", false
+}
+
+// compile 校验规则并预编译正则
+func (r *Rule) compile() error {
+	if r.Pattern == "" || r.Reply == "" {
+		return fmt.Errorf("关键词和回复都不能为空")
+	}
+	switch r.Match {
+	case MatchExact, MatchContains:
+	case MatchRegex:
+		re, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return fmt.Errorf("正则表达式有误: %v", err)
+		}
+		r.re = re
+	default:
+		return fmt.Errorf("未知的匹配方式 %s", r.Match)
+	}
+	return nil
+}
+
+// matches 判断消息是否命中规则，正则规则同时返回捕获的分组
+func (r *Rule) matches(content string) ([]string, bool) {
+	content = strings.TrimSpace(content)
+	switch r.Match {
+	case MatchExact:
+		return nil, content == r.Pattern
+	case MatchContains:
+		return nil, strings.Contains(content, r.Pattern)
+	case MatchRegex:
+		if r.re == nil {
+			return nil, false
+		}
+		groups := r.re.FindStringSubmatch(content)
+		return groups, groups != nil
+	}
+	return nil, false
+}
+
+// coolingDown 判断规则在群里是否处于冷却中
+func (r *Rule) coolingDown(groupID int64, now time.Time) bool {
+	if r.CooldownSec <= 0 {
+		return false
+	}
+	last, ok := lastFired[cooldownKey(groupID, r.ID)]
+	return ok && now.Sub(last) < time.Duration(r.CooldownSec)*time.Second
+}
+
+// render 替换回复中的占位符：{nickname} 发送者昵称、{at} @发送者、{qq} 发送者 QQ、
+// {time} 当前时间、{date} 当前日期、{weekday} 星期几、{1}~{9} 正则捕获的分组
+// 昵称和捕获分组来自用户，按 CQ 文本转义，只有 {at} 是真正的 CQ 码
+func render(reply string, event common.QQEvent, groups []string, now time.Time) string {
+	now = now.In(common.Location)
+	pairs := []string{
+		"{nickname}", common.EscapeCQText(storage.GetNickname(event.GroupID, event.UserID)),
+		"{at}", fmt.Sprintf("[CQ:at,qq=%d]", event.UserID),
+		"{qq}", strconv.FormatInt(event.UserID, 10),
+		"{time}", now.Format("15:04"),
+		"{date}", now.Format("2006-01-02"),
+		"{weekday}", []string{"周日", "周一", "周二", "周三", "周四", "周五", "周六"}[now.Weekday()],
+	}
+	for i := 1; i <= 9; i++ {
+		value := ""
+		if i < len(groups) {
+			value = groups[i]
+		}
+		pairs = append(pairs, fmt.Sprintf("{%d}", i), common.EscapeCQText(value))
+	}
+	return strings.NewReplacer(pairs...).Replace(reply)
+}
+
+func cooldownKey(groupID int64, id int) string {
+	return fmt.Sprintf("%d:%d", groupID, id)
+}
+
+// sortRules 按优先级排序，优先级相同时先添加的在前
+func sortRules(list []*Rule) {
+	sort.SliceStable(list, func(i, j int) bool {
+		if list[i].Priority != list[j].Priority {
+			return list[i].Priority < list[j].Priority
+		}
+		return list[i].ID < list[j].ID
+	})
+}
+
+// loadRulesLocked 首次使用时从存储加载，并预编译正则
+func loadRulesLocked() {
+	if rules != nil {
+		return
+	}
+	rules = make(map[int64]*groupRules)
+	storage.LoadData(rulesDataName, &rules)
+	for groupID, g := range rules {
+		for _, r := range g.Rules {
+			if err := r.compile(); err != nil {
+				log.Printf("[自动回复] 群%d 规则 #%d 无效: %v", groupID, r.ID, err)
+			}
+		}
+		sortRules(g.Rules)
+	}
+}
+
+// saveRulesLocked 保存到存储
+func saveRulesLocked() {
+	if err := storage.SaveData(rulesDataName, rules); err != nil {
+		log.Printf("[自动回复] 保存失败: %v", err)
+	}
+}
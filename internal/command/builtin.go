@@ -0,0 +1,119 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"QQBot/internal/handler"
+)
+
+func init() {
+	Register(&Command{
+		Name:        "help",
+		Aliases:     []string{"帮助"},
+		Description: "查看可用指令",
+		Args:        []Arg{{Name: "指令", Optional: true}},
+		Run:         runHelp,
+	})
+	Register(&Command{
+		Name:        "handler",
+		Aliases:     []string{"处理器"},
+		Description: "管理本群启用的功能",
+		Permission:  PermAdmin,
+		GroupOnly:   true,
+		Subcommands: []*Command{
+			{Name: "list", Aliases: []string{"列表"}, Description: "查看本群功能开关", Run: runHandlerList},
+			{Name: "on", Aliases: []string{"开启"}, Description: "开启功能", Args: []Arg{{Name: "名称"}}, Run: runHandlerToggle(true)},
+			{Name: "off", Aliases: []string{"关闭"}, Description: "关闭功能", Args: []Arg{{Name: "名称"}}, Run: runHandlerToggle(false)},
+			{Name: "stats", Aliases: []string{"统计"}, Description: "查看各功能自启动以来的运行次数、耗时和 panic 次数", Permission: PermMaster, Run: runHandlerStats},
+		},
+	})
+}
+
+// runHelp 列出调用者可用的指令，或显示某个指令的详细用法
+func runHelp(ctx *Context) error {
+	if ctx.Has("指令") {
+		cmd := Lookup(ctx.String("指令"))
+		if cmd == nil || !cmd.available(ctx.Event, ctx.Level) {
+			ctx.Reply(fmt.Sprintf("没有“%s”这个指令哦", ctx.String("指令")))
+			return nil
+		}
+		ctx.Reply(formatCommandHelp(cmd, cmd.Name, ctx.Event, ctx.Level))
+		return nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString("小牛会的指令：")
+	for _, cmd := range Commands() {
+		if !cmd.available(ctx.Event, ctx.Level) {
+			continue
+		}
+		sb.WriteString("\n" + displayPrefix() + cmd.Name)
+		if cmd.Description != "" {
+			sb.WriteString(" - " + cmd.Description)
+		}
+	}
+	sb.WriteString("\n发送 " + usageLine("help", nil) + " <指令> 查看详细用法")
+	ctx.Reply(sb.String())
+	return nil
+}
+
+// runHandlerList 列出所有处理器在本群的开关状态
+func runHandlerList(ctx *Context) error {
+	var sb strings.Builder
+	sb.WriteString("本群功能（按执行顺序）：")
+	for _, h := range handler.Handlers() {
+		state := "开启"
+		if !handler.IsEnabled(ctx.Event.GroupID, h.Name()) {
+			state = "关闭"
+		}
+		sb.WriteString(fmt.Sprintf("\n%s：%s", h.Name(), state))
+	}
+	ctx.Reply(sb.String())
+	return nil
+}
+
+// runHandlerStats 列出各处理器自启动以来的运行统计（全局，不区分群）
+func runHandlerStats(ctx *Context) error {
+	stats := handler.Stats()
+	if len(stats) == 0 {
+		ctx.Reply("还没有运行统计")
+		return nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString("各功能运行统计（自启动以来）：")
+	for _, s := range stats {
+		avg := time.Duration(0)
+		if s.Count > 0 {
+			avg = s.Total / time.Duration(s.Count)
+		}
+		sb.WriteString(fmt.Sprintf("\n%s：%d 次，平均 %v，最长 %v", s.Name, s.Count, avg.Round(time.Millisecond), s.Max.Round(time.Millisecond)))
+		if s.Panics > 0 {
+			sb.WriteString(fmt.Sprintf("，panic %d 次", s.Panics))
+		}
+	}
+	ctx.Reply(sb.String())
+	return nil
+}
+
+// runHandlerToggle 开启或关闭本群的某个处理器
+func runHandlerToggle(enabled bool) func(ctx *Context) error {
+	return func(ctx *Context) error {
+		name := ctx.String("名称")
+		if _, ok := handler.Lookup(name); !ok {
+			return Usagef("没有名为 %s 的功能", name)
+		}
+		if name == "command" && !enabled {
+			return Usagef("指令功能不能关闭，否则就没法再打开啦")
+		}
+		handler.SetEnabled(ctx.Event.GroupID, name, enabled)
+		if enabled {
+			ctx.Reply("已在本群开启 " + name)
+		} else {
+			ctx.Reply("已在本群关闭 " + name)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,173 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	"QQBot/internal/common"
+)
+
+// Permission 指令权限等级
+type Permission int
+
+const (
+	PermUser   Permission = iota // 所有人
+	PermAdmin                    // 群主/群管理员、ADMIN_QQ 中的用户（主人也可用）
+	PermMaster                   // 仅主人
+)
+
+// ArgType 参数类型
+type ArgType int
+
+const (
+	ArgString ArgType = iota // 单个参数（支持引号包裹含空格的内容）
+	ArgInt                   // 整数
+	ArgQQ                    // QQ 号或群号，也可以直接 @ 某人
+	ArgRest                  // 剩余所有内容，必须是最后一个参数
+)
+
+// Arg 参数定义
+type Arg struct {
+	Name     string
+	Type     ArgType
+	Optional bool
+}
+
+// Command 指令定义
+type Command struct {
+	Name        string
+	Aliases     []string
+	Description string
+	Permission  Permission
+	GroupOnly   bool       // 仅群聊可用
+	PrivateOnly bool       // 仅私聊可用
+	Args        []Arg      // 参数定义（有子指令时忽略）
+	Subcommands []*Command // 子指令，第一个参数为子指令名
+	Run         func(ctx *Context) error
+}
+
+// Context 指令执行上下文
+type Context struct {
+	Event common.QQEvent
+	Level Permission // 调用者的权限等级
+	Path  string     // 完整指令路径，如 "历史 清除"
+	args  map[string]interface{}
+}
+
+// ErrUsage 表示用法错误，返回该错误时会回复指令用法
+var ErrUsage = errors.New("用法错误")
+
+// Usagef 返回带说明的用法错误
+func Usagef(format string, a ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrUsage, fmt.Sprintf(format, a...))
+}
+
+var (
+	commandsMu sync.RWMutex
+	commands   []*Command
+)
+
+// Register 注册顶层指令，名称或别名冲突时忽略
+func Register(cmd *Command) {
+	commandsMu.Lock()
+	defer commandsMu.Unlock()
+
+	for _, name := range append([]string{cmd.Name}, cmd.Aliases...) {
+		if findIn(commands, name) != nil {
+			log.Printf("[指令] 名称冲突，忽略: %s", name)
+			return
+		}
+	}
+	commands = append(commands, cmd)
+}
+
+// Commands 返回所有已注册的顶层指令
+func Commands() []*Command {
+	commandsMu.RLock()
+	defer commandsMu.RUnlock()
+	return append([]*Command(nil), commands...)
+}
+
+// Lookup 按名称或别名查找顶层指令
+func Lookup(name string) *Command {
+	commandsMu.RLock()
+	defer commandsMu.RUnlock()
+	return findIn(commands, name)
+}
+
+// findIn 在指令列表中按名称或别名查找（忽略大小写）
+func findIn(list []*Command, name string) *Command {
+	for _, cmd := range list {
+		if strings.EqualFold(cmd.Name, name) {
+			return cmd
+		}
+		for _, alias := range cmd.Aliases {
+			if strings.EqualFold(alias, name) {
+				return cmd
+			}
+		}
+	}
+	return nil
+}
+
+// LevelOf 获取消息发送者的权限等级
+func LevelOf(event common.QQEvent) Permission {
+	if common.MasterQQNumber > 0 && event.UserID == common.MasterQQNumber {
+		return PermMaster
+	}
+	if event.SenderRole == "owner" || event.SenderRole == "admin" {
+		return PermAdmin
+	}
+	for _, qq := range common.AdminQQNumbers {
+		if qq == event.UserID {
+			return PermAdmin
+		}
+	}
+	return PermUser
+}
+
+// available 判断指令在当前场景下对调用者是否可用
+func (c *Command) available(event common.QQEvent, level Permission) bool {
+	if level < c.Permission {
+		return false
+	}
+	if c.GroupOnly && event.MsgType != "group" {
+		return false
+	}
+	if c.PrivateOnly && event.MsgType != "private" {
+		return false
+	}
+	return true
+}
+
+// String 获取字符串参数（未提供时为空字符串）
+func (c *Context) String(name string) string {
+	v, _ := c.args[name].(string)
+	return v
+}
+
+// Int 获取整数参数（未提供时为 0）
+func (c *Context) Int(name string) int {
+	v, _ := c.args[name].(int)
+	return v
+}
+
+// QQ 获取 QQ 号/群号参数（未提供时为 0）
+func (c *Context) QQ(name string) int64 {
+	v, _ := c.args[name].(int64)
+	return v
+}
+
+// Has 判断可选参数是否提供
+func (c *Context) Has(name string) bool {
+	_, ok := c.args[name]
+	return ok
+}
+
+// Reply 回复调用者
+func (c *Context) Reply(text string) {
+	common.SendReply(c.Event, text)
+}
@@ -0,0 +1,178 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"QQBot/internal/common"
+	"QQBot/internal/handler"
+)
+
+func init() {
+	handler.Register(commandHandler{})
+}
+
+// commandHandler 指令处理器：带前缀且指令存在时执行指令
+// 符号前缀（如 "/"）下的未知指令会提示查看帮助，文字前缀（如 "小牛"）下的未知指令交给后续处理器（AI）
+type commandHandler struct{}
+
+func (commandHandler) Name() string  { return "command" }
+func (commandHandler) Priority() int { return 30 }
+
+func (commandHandler) Match(event common.QQEvent) bool {
+	tokens, symbolPrefix, ok := parseCommandLine(event)
+	if !ok {
+		return false
+	}
+	return symbolPrefix || Lookup(tokens[0]) != nil
+}
+
+func (commandHandler) Handle(_ context.Context, event common.QQEvent) handler.Result {
+	tokens, _, _ := parseCommandLine(event)
+	Execute(event, tokens)
+	return handler.Stop
+}
+
+// parseCommandLine 解析带前缀的指令行，返回切分后的参数（第一个为指令名）
+func parseCommandLine(event common.QQEvent) (tokens []string, symbolPrefix bool, ok bool) {
+	content := strings.TrimSpace(normalizeMentions(event))
+	// 群聊中允许先 @机器人 再发指令
+	if common.BotQQNumber > 0 {
+		content = strings.TrimSpace(strings.TrimPrefix(content, fmt.Sprintf("@%d", common.BotQQNumber)))
+	}
+	rest, symbolPrefix, ok := splitPrefix(content)
+	if !ok || rest == "" {
+		return nil, false, false
+	}
+	tokens, err := tokenize(rest)
+	if err != nil || len(tokens) == 0 {
+		// 引号不闭合时按空白切分，交给参数解析报错
+		tokens = strings.Fields(rest)
+	}
+	return tokens, symbolPrefix, len(tokens) > 0
+}
+
+// Execute 执行指令（tokens[0] 为指令名）
+func Execute(event common.QQEvent, tokens []string) {
+	cmd := Lookup(tokens[0])
+	if cmd == nil {
+		common.SendReply(event, fmt.Sprintf("没有“%s”这个指令哦，发送 %s 查看可用指令", tokens[0], usageLine("help", nil)))
+		return
+	}
+
+	level := LevelOf(event)
+	path := cmd.Name
+	tokens = tokens[1:]
+
+	for {
+		if reason := denyReason(cmd, event, level); reason != "" {
+			common.SendReply(event, reason)
+			return
+		}
+		if len(cmd.Subcommands) == 0 {
+			break
+		}
+		var sub *Command
+		if len(tokens) > 0 {
+			sub = findIn(cmd.Subcommands, tokens[0])
+		}
+		if sub == nil {
+			common.SendReply(event, formatCommandHelp(cmd, path, event, level))
+			return
+		}
+		cmd = sub
+		path += " " + sub.Name
+		tokens = tokens[1:]
+	}
+
+	args, err := parseArgs(cmd, tokens)
+	if err != nil {
+		common.SendReply(event, fmt.Sprintf("%s\n用法：%s", err.Error(), usageLine(path, cmd.Args)))
+		return
+	}
+
+	log.Printf("[指令] 用户:%d 群:%d 执行: %s %v", event.UserID, event.GroupID, path, args)
+	ctx := &Context{Event: event, Level: level, Path: path, args: args}
+	if err := cmd.Run(ctx); err != nil {
+		if errors.Is(err, ErrUsage) {
+			common.SendReply(event, fmt.Sprintf("%s\n用法：%s", strings.TrimPrefix(err.Error(), ErrUsage.Error()+": "), usageLine(path, cmd.Args)))
+			return
+		}
+		log.Printf("[指令] %s 执行失败: %v", path, err)
+		common.SendReply(event, "出错了："+err.Error())
+	}
+}
+
+// denyReason 返回调用者不能使用指令的原因（空字符串表示可以使用）
+func denyReason(cmd *Command, event common.QQEvent, level Permission) string {
+	switch {
+	case level < cmd.Permission && cmd.Permission == PermMaster:
+		return "这个指令只有爸爸能用哦"
+	case level < cmd.Permission:
+		return "这个指令需要管理员权限哦"
+	case cmd.GroupOnly && event.MsgType != "group":
+		return "这个指令只能在群里用哦"
+	case cmd.PrivateOnly && event.MsgType != "private":
+		return "这个指令只能私聊小牛用哦"
+	}
+	return ""
+}
+
+// displayPrefix 帮助信息中展示的指令前缀
+func displayPrefix() string {
+	prefix := common.CommandPrefixes[0]
+	if _, symbolPrefix, ok := splitPrefix(prefix + " x"); ok && !symbolPrefix {
+		return prefix + " "
+	}
+	return prefix
+}
+
+// usageLine 生成用法行，如 "/历史 清除 <QQ> [条数]"
+func usageLine(path string, args []Arg) string {
+	var sb strings.Builder
+	sb.WriteString(displayPrefix())
+	sb.WriteString(path)
+	for _, arg := range args {
+		name := arg.Name
+		if arg.Type == ArgRest {
+			name += "..."
+		}
+		if arg.Optional {
+			sb.WriteString(" [" + name + "]")
+		} else {
+			sb.WriteString(" <" + name + ">")
+		}
+	}
+	return sb.String()
+}
+
+// formatCommandHelp 生成单个指令（含可用子指令）的帮助信息
+func formatCommandHelp(cmd *Command, path string, event common.QQEvent, level Permission) string {
+	var sb strings.Builder
+	if cmd.Description != "" {
+		sb.WriteString(cmd.Description + "\n")
+	}
+	if len(cmd.Aliases) > 0 {
+		sb.WriteString("别名：" + strings.Join(cmd.Aliases, "、") + "\n")
+	}
+
+	if len(cmd.Subcommands) == 0 {
+		sb.WriteString("用法：" + usageLine(path, cmd.Args))
+		return sb.String()
+	}
+
+	sb.WriteString("子指令：")
+	for _, sub := range cmd.Subcommands {
+		if !sub.available(event, level) {
+			continue
+		}
+		sb.WriteString("\n" + usageLine(path+" "+sub.Name, sub.Args))
+		if sub.Description != "" {
+			sb.WriteString(" - " + sub.Description)
+		}
+	}
+	return sb.String()
+}
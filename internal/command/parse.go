@@ -0,0 +1,151 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"QQBot/internal/common"
+	"QQBot/internal/storage"
+)
+
+// 成对的引号（开 -> 闭）
+var quotePairs = map[rune]rune{
+	'"': '"',
+	'“': '”',
+	'‘': '’',
+}
+
+// splitPrefix 去掉指令前缀，返回剩余内容，以及前缀是否为符号（如 "/"）
+// 文字前缀（如 "小牛"）后面必须跟空白，避免 "小牛电动车" 之类被误认为指令
+func splitPrefix(content string) (rest string, symbolPrefix bool, ok bool) {
+	prefixes := append([]string(nil), common.CommandPrefixes...)
+	sort.Slice(prefixes, func(i, j int) bool { return len(prefixes[i]) > len(prefixes[j]) })
+
+	for _, prefix := range prefixes {
+		if !strings.HasPrefix(content, prefix) {
+			continue
+		}
+		rest = content[len(prefix):]
+		last, _ := utf8.DecodeLastRuneInString(prefix)
+		symbolPrefix = unicode.IsPunct(last) || unicode.IsSymbol(last)
+		if !symbolPrefix {
+			first, _ := utf8.DecodeRuneInString(rest)
+			if !unicode.IsSpace(first) {
+				continue
+			}
+		}
+		return strings.TrimSpace(rest), symbolPrefix, true
+	}
+	return "", false, false
+}
+
+// normalizeMentions 把消息中格式化后的 @（"@【角色】昵称"）替换为 "@QQ号"，便于解析 ArgQQ 参数
+func normalizeMentions(event common.QQEvent) string {
+	content := event.Content
+	for _, qq := range event.AtQQs {
+		formatted := storage.FormatAtMessage(event.GroupID, qq)
+		content = strings.Replace(content, formatted, fmt.Sprintf(" @%d ", qq), 1)
+	}
+	return content
+}
+
+// tokenize 按空白切分参数，引号包裹的内容视为一个参数
+func tokenize(s string) ([]string, error) {
+	var tokens []string
+	var current strings.Builder
+	inToken := false
+	var closing rune
+
+	for _, r := range s {
+		switch {
+		case closing != 0:
+			if r == closing {
+				closing = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case quotePairs[r] != 0:
+			closing = quotePairs[r]
+			inToken = true
+		case unicode.IsSpace(r):
+			if inToken {
+				tokens = append(tokens, current.String())
+				current.Reset()
+				inToken = false
+			}
+		default:
+			current.WriteRune(r)
+			inToken = true
+		}
+	}
+
+	if closing != 0 {
+		return nil, errors.New("引号没有闭合")
+	}
+	if inToken {
+		tokens = append(tokens, current.String())
+	}
+	return tokens, nil
+}
+
+// ParseProbability 解析 "0.3" 或 "30%" 形式的概率（0~1），大于 1 的数也按百分比理解；格式不对时返回用法错误
+func ParseProbability(s string) (float64, error) {
+	percent := strings.HasSuffix(s, "%")
+	p, err := strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64)
+	if err != nil {
+		return 0, Usagef("概率应为 0~1 的小数或百分比")
+	}
+	if percent || p > 1 {
+		p /= 100
+	}
+	if p < 0 || p > 1 {
+		return 0, Usagef("概率应在 0 到 100%% 之间")
+	}
+	return p, nil
+}
+
+// parseArgs 按指令的参数定义解析参数
+func parseArgs(cmd *Command, tokens []string) (map[string]interface{}, error) {
+	args := make(map[string]interface{})
+	i := 0
+	for _, arg := range cmd.Args {
+		if i >= len(tokens) {
+			if !arg.Optional {
+				return nil, fmt.Errorf("缺少参数 <%s>", arg.Name)
+			}
+			continue
+		}
+
+		switch arg.Type {
+		case ArgRest:
+			args[arg.Name] = strings.Join(tokens[i:], " ")
+			i = len(tokens)
+			continue
+		case ArgInt:
+			n, err := strconv.Atoi(tokens[i])
+			if err != nil {
+				return nil, fmt.Errorf("参数 <%s> 应该是数字", arg.Name)
+			}
+			args[arg.Name] = n
+		case ArgQQ:
+			qq, err := strconv.ParseInt(strings.TrimPrefix(tokens[i], "@"), 10, 64)
+			if err != nil || qq <= 0 {
+				return nil, fmt.Errorf("参数 <%s> 应该是 QQ 号/群号或 @某人", arg.Name)
+			}
+			args[arg.Name] = qq
+		default:
+			args[arg.Name] = tokens[i]
+		}
+		i++
+	}
+
+	if i < len(tokens) {
+		return nil, fmt.Errorf("多余的参数: %s", strings.Join(tokens[i:], " "))
+	}
+	return args, nil
+}
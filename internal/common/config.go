@@ -4,13 +4,20 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata" // 内置时区数据，Windows 等没有系统时区库的环境也能解析时区
 )
 
 // 配置常量
 const (
-	ListenPort             = ":8080"
-	DeepSeekBaseURL        = "https://api.deepseek.com/chat/completions"
-	RepeatMessageQueueSize = 3 // 连续相同消息检测队列大小
+	ListenPort      = ":8080"
+	DeepSeekBaseURL = "https://api.deepseek.com/chat/completions"
+
+	HeartbeatMissedLimit  = 3               // 连续错过多少次心跳后认为连接已失效
+	OutageNotifyThreshold = 5 * time.Minute // 断线超过此时长，重连后私聊通知主人
+
+	ShutdownTimeout = 15 * time.Second // 退出时等待 AI 任务完成的最长时间
 )
 
 // 配置变量
@@ -19,8 +26,90 @@ var (
 	BotQQNumber              int64
 	MasterQQNumber           int64
 	MasterGirlFriendQQNumber int64
+	AdminQQNumbers           []int64 // 额外的管理员 QQ 号（可使用管理员指令）
+
+	// 好友/群请求自动审批策略
+	RequestUserWhitelist  []int64  // 发起人在名单内时自动同意
+	RequestGroupWhitelist []int64  // 目标群在名单内时自动同意（加群申请、入群邀请）
+	RequestKeywords       []string // 验证消息包含任一关键词时自动同意
+
+	// 消息处理器配置
+	HandlerPriorities  map[string]int // 处理器名 -> 优先级，覆盖处理器自带的默认优先级
+	HandlerPassthrough []string       // 处理完后仍继续交给后续处理器的处理器名
+
+	CommandPrefixes []string // 指令前缀，如 "/"、"小牛"
+
+	// AI 调用冷却与配额（主人、主人女朋友、管理员不受限制）
+	AIUserBurst     int           // 每个用户可连续触发的次数（令牌桶容量）
+	AIUserRefill    time.Duration // 每个用户恢复一次触发机会所需时间
+	AIGroupBurst    int           // 每个群可连续触发的次数
+	AIGroupRefill   time.Duration // 每个群恢复一次触发机会所需时间
+	AIDailyRequests int           // 每个用户每天最多触发次数，0 表示不限
+	AIDailyTokens   int           // 每个用户每天最多消耗的 token 数，0 表示不限
+
+	ModelPrices map[string]ModelPrice // 模型 -> 价格，用于估算费用
+
+	// AI 任务队列
+	AIWorkers       int    // 同时进行的 AI 调用数上限
+	AIQueueSize     int    // 每个会话（私聊用户/群）排队任务数上限
+	AIQueueOverflow string // 队列满时的策略："drop-oldest"（丢弃最早的排队任务）或 "drop-newest"（丢弃新任务）
+
+	// 存储后端
+	StorageBackend string // "json"（默认，每个对象一个文件）或 "bolt"（嵌入式数据库）
+	StorageDBPath  string // bolt 数据库文件路径
+
+	ArchiveRetentionDays int // 群消息归档保留天数，0 表示永久保留
+	UsageRetentionDays   int // 用量明细和每日汇总保留天数，0 表示永久保留
+
+	// 复读默认策略（管理员可按群修改）
+	RepeatThreshold        int           // 连续多少条相同消息触发复读
+	RepeatDistinctSenders  bool          // 是否要求这些消息来自不同的人（同一人刷屏不算）
+	RepeatCooldown         time.Duration // 同一句话在同一群复读后的冷却时间
+	RepeatProbability      float64       // 达到条件后实际复读的概率
+	RepeatBreakProbability float64       // 达到条件后改为"打断复读"的概率
+
+	// 群聊关键词"小牛"的触发判断
+	TriggerExcludePhrases []string // 包含"小牛"但不是在叫小牛的词（如 "小牛电动车"），判断前先去掉
+	TriggerAmbiguous      string   // 规则无法确定是否在叫小牛时："ignore"（不回复）、"reply"（回复）或 "classify"（交给 AI 判断）
+
+	// AI 回复发送前的安全过滤
+	SafetyWordsFile     string // 敏感词文件，每行一个词，可写 "词|策略"
+	SafetyDefaultPolicy string // 敏感词未指定策略时的处理："mask"（遮住）、"regenerate"（重新生成）或 "refuse"（拒绝回复）
+	SafetyLeakPolicy    string // 疑似泄露系统提示词时的处理："regenerate" 或 "refuse"
+	SafetyStripStage    bool   // 是否去掉回复中的旁白和动作描写，如 "（歪头）"
+
+	// 群聊追问窗口：小牛回复某人后，此人接下来的消息不用再叫"小牛"
+	FollowUpWindow         time.Duration // 窗口时长（每次回复后重新计时），0 表示关闭
+	FollowUpMaxInterleaved int           // 期间其他人插入的消息超过此数量视为话题已转移，窗口结束
+
+	// 群聊主动插话默认策略（需管理员按群开启，可按群修改）
+	ChimeProbability float64       // 群聊活跃时每条消息后考虑插话的概率
+	ChimeWindow      time.Duration // 统计群聊活跃度的时间窗口
+	ChimeMinMessages int           // 窗口内至少多少条群友消息才算活跃
+	ChimeMinSpeakers int           // 窗口内至少多少人发言才算活跃
+	ChimeMinInterval time.Duration // 两次插话的最小间隔
+	ChimeDailyMax    int           // 每个群每天最多插话次数
+
+	// 群聊 @主人 的汇总转告
+	AtMasterWindow         time.Duration // 同一群的 @ 在此时间内合并为一条汇总，0 表示每次 @ 立即转告
+	AtMasterUrgentKeywords []string      // 包含任一关键词的 @ 立即转告（免打扰时段也会转告）
+	QuietHoursStart        int           // 免打扰开始时刻（当天第几分钟），-1 表示未设置
+	QuietHoursEnd          int           // 免打扰结束时刻（当天第几分钟）
+
+	// 定时任务与提醒
+	ScheduleTimezone      string         // 默认时区（IANA 名称，如 "Asia/Shanghai"）
+	Location              *time.Location // ScheduleTimezone 对应的时区，无法加载时为本地时区
+	ScheduleCatchUp       string         // 离线期间错过的任务："once"（上线后补执行一次）或 "skip"（跳过）
+	ScheduleCatchUpWindow time.Duration  // 只补执行错过不超过此时长的任务
 )
 
+// ModelPrice 模型价格（元 / 百万 token）
+type ModelPrice struct {
+	CacheHitInput  float64 // 输入（缓存命中）
+	CacheMissInput float64 // 输入（缓存未命中）
+	Output         float64 // 输出
+}
+
 func init() {
 	DeepSeekAPIKey = os.Getenv("DEEPSEEK_API_KEY")
 
@@ -36,4 +125,286 @@ func init() {
 	BotQQNumber, _ = strconv.ParseInt(botQQStr, 10, 64)
 	MasterQQNumber, _ = strconv.ParseInt(masterQQStr, 10, 64)
 	MasterGirlFriendQQNumber, _ = strconv.ParseInt(masterGirlFriendQQStr, 10, 64)
+
+	AdminQQNumbers = parseInt64List(os.Getenv("ADMIN_QQ"))
+
+	RequestUserWhitelist = parseInt64List(os.Getenv("REQUEST_USER_WHITELIST"))
+	RequestGroupWhitelist = parseInt64List(os.Getenv("REQUEST_GROUP_WHITELIST"))
+	RequestKeywords = parseStringList(os.Getenv("REQUEST_KEYWORDS"))
+
+	HandlerPriorities = parseIntMap(os.Getenv("HANDLER_PRIORITY"))
+	HandlerPassthrough = parseStringList(os.Getenv("HANDLER_PASSTHROUGH"))
+
+	CommandPrefixes = parseStringList(os.Getenv("COMMAND_PREFIXES"))
+	if len(CommandPrefixes) == 0 {
+		CommandPrefixes = []string{"/", "小牛"}
+	}
+
+	AIUserBurst = getEnvInt("AI_USER_BURST", 3)
+	AIUserRefill = getEnvDuration("AI_USER_REFILL", 20*time.Second)
+	AIGroupBurst = getEnvInt("AI_GROUP_BURST", 10)
+	AIGroupRefill = getEnvDuration("AI_GROUP_REFILL", 6*time.Second)
+	AIDailyRequests = getEnvInt("AI_DAILY_REQUESTS", 100)
+	AIDailyTokens = getEnvInt("AI_DAILY_TOKENS", 100000)
+
+	ModelPrices = map[string]ModelPrice{
+		"deepseek-chat":     {CacheHitInput: 0.5, CacheMissInput: 2, Output: 8},
+		"deepseek-reasoner": {CacheHitInput: 1, CacheMissInput: 4, Output: 16},
+	}
+	for model, price := range parseModelPrices(os.Getenv("MODEL_PRICES")) {
+		ModelPrices[model] = price
+	}
+
+	AIWorkers = getEnvInt("AI_WORKERS", 4)
+	AIQueueSize = getEnvInt("AI_QUEUE_SIZE", 5)
+	AIQueueOverflow = os.Getenv("AI_QUEUE_OVERFLOW")
+	if AIQueueOverflow != "drop-newest" {
+		AIQueueOverflow = "drop-oldest"
+	}
+
+	StorageBackend = os.Getenv("STORAGE_BACKEND")
+	if StorageBackend == "" {
+		StorageBackend = "json"
+	}
+	StorageDBPath = os.Getenv("STORAGE_DB_PATH")
+	if StorageDBPath == "" {
+		StorageDBPath = "data/qqbot.db"
+	}
+
+	ArchiveRetentionDays = getEnvInt("ARCHIVE_RETENTION_DAYS", 30)
+	UsageRetentionDays = getEnvInt("USAGE_RETENTION_DAYS", 90)
+
+	RepeatThreshold = getEnvInt("REPEAT_THRESHOLD", 3)
+	if RepeatThreshold < 2 {
+		log.Printf("⚠️  警告: REPEAT_THRESHOLD 应不小于 2（为 %d 时每条消息都会被复读），使用默认值 3", RepeatThreshold)
+		RepeatThreshold = 3
+	}
+	RepeatDistinctSenders = getEnvBool("REPEAT_DISTINCT_SENDERS", true)
+	RepeatCooldown = getEnvDuration("REPEAT_COOLDOWN", 10*time.Minute)
+	RepeatProbability = getEnvFloat("REPEAT_PROBABILITY", 1)
+	RepeatBreakProbability = getEnvFloat("REPEAT_BREAK_PROBABILITY", 0)
+
+	TriggerExcludePhrases = parseStringList(os.Getenv("TRIGGER_EXCLUDE"))
+	if len(TriggerExcludePhrases) == 0 {
+		TriggerExcludePhrases = []string{"小牛电动车", "小牛电动", "小牛电车", "小牛车", "小牛肉", "小牛排", "小牛皮", "小牛队"}
+	}
+	TriggerAmbiguous = os.Getenv("TRIGGER_AMBIGUOUS")
+	if TriggerAmbiguous != "reply" && TriggerAmbiguous != "classify" {
+		TriggerAmbiguous = "ignore"
+	}
+
+	SafetyWordsFile = os.Getenv("SAFETY_WORDS_FILE")
+	if SafetyWordsFile == "" {
+		SafetyWordsFile = "data/sensitive_words.txt"
+	}
+	SafetyDefaultPolicy = os.Getenv("SAFETY_DEFAULT_POLICY")
+	if SafetyDefaultPolicy != "regenerate" && SafetyDefaultPolicy != "refuse" {
+		SafetyDefaultPolicy = "mask"
+	}
+	SafetyLeakPolicy = os.Getenv("SAFETY_LEAK_POLICY")
+	if SafetyLeakPolicy != "refuse" {
+		SafetyLeakPolicy = "regenerate"
+	}
+	SafetyStripStage = getEnvBool("SAFETY_STRIP_STAGE", true)
+
+	FollowUpWindow = getEnvDuration("FOLLOWUP_WINDOW", 2*time.Minute)
+	FollowUpMaxInterleaved = getEnvInt("FOLLOWUP_MAX_INTERLEAVED", 4)
+
+	ChimeProbability = getEnvFloat("CHIME_PROBABILITY", 0.1)
+	ChimeWindow = getEnvDuration("CHIME_WINDOW", 5*time.Minute)
+	ChimeMinMessages = getEnvInt("CHIME_MIN_MESSAGES", 8)
+	ChimeMinSpeakers = getEnvInt("CHIME_MIN_SPEAKERS", 3)
+	ChimeMinInterval = getEnvDuration("CHIME_MIN_INTERVAL", 30*time.Minute)
+	ChimeDailyMax = getEnvInt("CHIME_DAILY_MAX", 10)
+
+	AtMasterWindow = getEnvDuration("AT_MASTER_WINDOW", 10*time.Minute)
+	AtMasterUrgentKeywords = parseStringList(os.Getenv("AT_MASTER_URGENT_KEYWORDS"))
+	if len(AtMasterUrgentKeywords) == 0 {
+		AtMasterUrgentKeywords = []string{"紧急", "急事", "救命"}
+	}
+	QuietHoursStart, QuietHoursEnd = parseClockRange(os.Getenv("QUIET_HOURS"))
+
+	ScheduleTimezone = os.Getenv("SCHEDULE_TIMEZONE")
+	if ScheduleTimezone == "" {
+		ScheduleTimezone = "Asia/Shanghai"
+	}
+	if loc, err := time.LoadLocation(ScheduleTimezone); err == nil {
+		Location = loc
+	} else {
+		log.Printf("⚠️  警告: 无法加载时区 %s，使用本地时区: %v", ScheduleTimezone, err)
+		Location = time.Local
+	}
+	ScheduleCatchUp = os.Getenv("SCHEDULE_CATCH_UP")
+	if ScheduleCatchUp != "skip" {
+		ScheduleCatchUp = "once"
+	}
+	ScheduleCatchUpWindow = getEnvDuration("SCHEDULE_CATCH_UP_WINDOW", 6*time.Hour)
+}
+
+// parseModelPrices 解析形如 "deepseek-chat=0.5:2:8" 的价格列表（缓存命中输入:缓存未命中输入:输出）
+func parseModelPrices(s string) map[string]ModelPrice {
+	result := make(map[string]ModelPrice)
+	for _, part := range parseStringList(s) {
+		model, values, ok := strings.Cut(part, "=")
+		fields := strings.Split(values, ":")
+		if !ok || len(fields) != 3 {
+			log.Printf("⚠️  警告: 无法解析模型价格 %q，已忽略", part)
+			continue
+		}
+		var prices [3]float64
+		valid := true
+		for i, f := range fields {
+			p, err := strconv.ParseFloat(strings.TrimSpace(f), 64)
+			if err != nil {
+				valid = false
+				break
+			}
+			prices[i] = p
+		}
+		if !valid {
+			log.Printf("⚠️  警告: 无法解析模型价格 %q，已忽略", part)
+			continue
+		}
+		result[strings.TrimSpace(model)] = ModelPrice{CacheHitInput: prices[0], CacheMissInput: prices[1], Output: prices[2]}
+	}
+	return result
+}
+
+// parseClockRange 解析形如 "23:00-08:00" 的时段，返回开始和结束是当天第几分钟，未设置或无法解析时返回 -1, -1
+func parseClockRange(s string) (start int, end int) {
+	if s == "" {
+		return -1, -1
+	}
+	from, to, ok := strings.Cut(s, "-")
+	if ok {
+		start, end = parseClock(from), parseClock(to)
+		if start >= 0 && end >= 0 && start != end {
+			return start, end
+		}
+	}
+	log.Printf("⚠️  警告: 无法解析时段 %q（格式如 23:00-08:00），已忽略", s)
+	return -1, -1
+}
+
+// parseClock 解析 "08:00" 或 "8" 形式的时刻，返回当天第几分钟，无法解析时返回 -1
+func parseClock(s string) int {
+	hour, minute, _ := strings.Cut(strings.TrimSpace(s), ":")
+	h, err := strconv.Atoi(hour)
+	if err != nil || h < 0 || h > 23 {
+		return -1
+	}
+	m := 0
+	if minute != "" {
+		if m, err = strconv.Atoi(minute); err != nil || m < 0 || m > 59 {
+			return -1
+		}
+	}
+	return h*60 + m
+}
+
+// InQuietHours 判断某个时刻是否处于免打扰时段（支持跨午夜，如 23:00-08:00）
+func InQuietHours(t time.Time) bool {
+	if QuietHoursStart < 0 {
+		return false
+	}
+	now := t.Hour()*60 + t.Minute()
+	if QuietHoursStart < QuietHoursEnd {
+		return now >= QuietHoursStart && now < QuietHoursEnd
+	}
+	return now >= QuietHoursStart || now < QuietHoursEnd
+}
+
+// getEnvInt 读取整数环境变量，未设置或无法解析时返回默认值
+func getEnvInt(name string, def int) int {
+	s := os.Getenv(name)
+	if s == "" {
+		return def
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		log.Printf("⚠️  警告: %s=%q 不是有效整数，使用默认值 %d", name, s, def)
+		return def
+	}
+	return n
+}
+
+// getEnvFloat 读取小数环境变量，未设置或无法解析时返回默认值
+func getEnvFloat(name string, def float64) float64 {
+	s := os.Getenv(name)
+	if s == "" {
+		return def
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		log.Printf("⚠️  警告: %s=%q 不是有效数字，使用默认值 %v", name, s, def)
+		return def
+	}
+	return f
+}
+
+// getEnvBool 读取布尔环境变量（true/false、1/0、on/off），未设置或无法解析时返回默认值
+func getEnvBool(name string, def bool) bool {
+	switch strings.ToLower(os.Getenv(name)) {
+	case "":
+		return def
+	case "1", "true", "yes", "on":
+		return true
+	case "0", "false", "no", "off":
+		return false
+	}
+	log.Printf("⚠️  警告: %s=%q 不是有效开关，使用默认值 %v", name, os.Getenv(name), def)
+	return def
+}
+
+// getEnvDuration 读取时长环境变量（如 "20s"、"5m"），未设置或无法解析时返回默认值
+func getEnvDuration(name string, def time.Duration) time.Duration {
+	s := os.Getenv(name)
+	if s == "" {
+		return def
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		log.Printf("⚠️  警告: %s=%q 不是有效时长，使用默认值 %v", name, s, def)
+		return def
+	}
+	return d
+}
+
+// parseStringList 解析逗号分隔的字符串列表，忽略空项
+func parseStringList(s string) []string {
+	var result []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			result = append(result, part)
+		}
+	}
+	return result
+}
+
+// parseInt64List 解析逗号分隔的数字列表（如 QQ 号、群号），忽略无法解析的项
+func parseInt64List(s string) []int64 {
+	var result []int64
+	for _, part := range parseStringList(s) {
+		if n, err := strconv.ParseInt(part, 10, 64); err == nil {
+			result = append(result, n)
+		} else {
+			log.Printf("⚠️  警告: 无法解析数字 %q，已忽略", part)
+		}
+	}
+	return result
+}
+
+// parseIntMap 解析形如 "a=1,b=2" 的键值列表，忽略无法解析的项
+func parseIntMap(s string) map[string]int {
+	result := make(map[string]int)
+	for _, part := range parseStringList(s) {
+		key, value, ok := strings.Cut(part, "=")
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if !ok || err != nil {
+			log.Printf("⚠️  警告: 无法解析配置项 %q，已忽略", part)
+			continue
+		}
+		result[strings.TrimSpace(key)] = n
+	}
+	return result
 }
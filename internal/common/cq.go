@@ -0,0 +1,19 @@
+package common
+
+import "strings"
+
+var (
+	cqTextEscaper  = strings.NewReplacer("&", "&amp;", "[", "&#91;", "]", "&#93;")
+	cqParamEscaper = strings.NewReplacer("&", "&amp;", "[", "&#91;", "]", "&#93;", ",", "&#44;")
+)
+
+// EscapeCQText 转义 CQ 码消息中的文本，用户输入（昵称、消息内容等）拼进 CQ 码消息前都要经过转义，
+// 否则其中的 [CQ:...] 会被当作 @全体成员、图片等真正的消息段发送
+func EscapeCQText(s string) string {
+	return cqTextEscaper.Replace(s)
+}
+
+// EscapeCQParam 转义 CQ 码中的参数值
+func EscapeCQParam(s string) string {
+	return cqParamEscaper.Replace(s)
+}
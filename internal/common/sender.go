@@ -3,6 +3,7 @@ package common
 import (
 	"log"
 	"sync"
+	"time"
 
 	"github.com/gorilla/websocket"
 )
@@ -26,34 +27,80 @@ func GetWebSocketConn() *websocket.Conn {
 	return wsConn
 }
 
-// ClearWebSocketConn 清除WebSocket连接
-func ClearWebSocketConn() {
+// ClearWebSocketConn 清除WebSocket连接（仅当当前连接仍是 conn 时，避免误清新连接）
+func ClearWebSocketConn(conn *websocket.Conn) {
 	connMu.Lock()
 	defer connMu.Unlock()
+	if wsConn == conn {
+		wsConn = nil
+	}
+}
+
+// CloseWebSocketConn 关闭并清除指定的WebSocket连接（用于心跳超时等场景）
+func CloseWebSocketConn(conn *websocket.Conn) {
+	connMu.Lock()
+	defer connMu.Unlock()
+	if wsConn == conn {
+		wsConn = nil
+	}
+	conn.Close()
+}
+
+// ShutdownWebSocketConn 发送关闭帧后关闭当前连接（用于程序退出）
+func ShutdownWebSocketConn() {
+	connMu.Lock()
+	defer connMu.Unlock()
+	if wsConn == nil {
+		return
+	}
+
+	msg := websocket.FormatCloseMessage(websocket.CloseGoingAway, "shutdown")
+	if err := wsConn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second)); err != nil {
+		log.Printf("[警告] 发送关闭帧失败: %v", err)
+	}
+	wsConn.Close()
 	wsConn = nil
 }
 
 // SendReply 发送回复消息
 func SendReply(e QQEvent, text string) {
+	params := map[string]interface{}{
+		"message_type": e.MsgType,
+		"user_id":      e.UserID,
+		"group_id":     e.GroupID,
+		"message":      text,
+	}
+	if SendAction("send_msg", params) {
+		log.Printf("[发送] -> 用户:%d 内容:%s", e.UserID, text)
+	}
+}
+
+// SendPrivateMessage 向指定用户发送私聊消息
+func SendPrivateMessage(userID int64, text string) {
+	if userID == 0 {
+		log.Println("[警告] 发送失败：私聊对象为空")
+		return
+	}
+	SendReply(QQEvent{MsgType: "private", UserID: userID}, text)
+}
+
+// SendAction 调用 OneBot 动作（如 set_friend_add_request），返回是否成功写出
+func SendAction(action string, params map[string]interface{}) bool {
 	connMu.Lock()
 	defer connMu.Unlock()
 	if wsConn == nil {
-		log.Println("[警告] 发送失败：WebSocket 连接为空")
-		return
+		log.Printf("[警告] %s 发送失败：WebSocket 连接为空", action)
+		return false
 	}
 
 	payload := map[string]interface{}{
-		"action": "send_msg",
-		"params": map[string]interface{}{
-			"message_type": e.MsgType,
-			"user_id":      e.UserID,
-			"group_id":     e.GroupID,
-			"message":      text,
-		},
+		"action": action,
+		"params": params,
 	}
 
 	if err := wsConn.WriteJSON(payload); err != nil {
-		log.Printf("[发送失败]: %v", err)
+		log.Printf("[发送失败] %s: %v", action, err)
+		return false
 	}
-	log.Printf("[发送] -> 用户:%d 内容:%s", e.UserID, text)
+	return true
 }
@@ -0,0 +1,50 @@
+package common
+
+import (
+	"sync"
+	"time"
+)
+
+// TickLoop 周期性执行任务的后台循环（定时任务、提醒、@主人汇总等），退出时可停止并等待当前这一轮执行完
+type TickLoop struct {
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// StartTickLoop 启动后台循环，每隔 interval 以当前时间调用一次 fn；immediate 为 true 时启动后先立即执行一次
+func StartTickLoop(interval time.Duration, immediate bool, fn func(now time.Time)) *TickLoop {
+	l := &TickLoop{stop: make(chan struct{}), done: make(chan struct{})}
+	go func() {
+		defer close(l.done)
+		if immediate {
+			fn(time.Now())
+		}
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-l.stop:
+				return
+			case now := <-ticker.C:
+				// 停止与到期同时发生时不再开始新的一轮
+				select {
+				case <-l.stop:
+					return
+				default:
+				}
+				fn(now)
+			}
+		}
+	}()
+	return l
+}
+
+// Stop 停止循环并等待正在执行的一轮结束，可重复调用，nil 时什么也不做
+func (l *TickLoop) Stop() {
+	if l == nil {
+		return
+	}
+	l.stopOnce.Do(func() { close(l.stop) })
+	<-l.done
+}
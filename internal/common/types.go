@@ -10,10 +10,41 @@ const (
 
 // QQEvent 表示一个QQ消息事件
 type QQEvent struct {
+	MessageID  int64
 	MsgType    string
 	UserID     int64
 	GroupID    int64
-	Content    string // 解析后的消息（@用户名 文本内容）
-	RawContent string // 原始 array 的 JSON（用于调试）
-	AtType     int    // @类型（AtNone/AtBot/AtMaster/AtOthers）
+	Content    string  // 解析后的消息（@用户名 文本内容）
+	RawContent string  // 原始 array 的 JSON（用于调试）
+	AtType     int     // @类型（AtNone/AtBot/AtMaster/AtOthers）
+	AtQQs      []int64 // 消息中按顺序 @ 的 QQ 号
+	SenderRole string  // 群聊中发送者的群身份（"owner"、"admin"、"member"）
+}
+
+// RequestEvent 表示一个好友/群请求事件（post_type 为 request）
+type RequestEvent struct {
+	RequestType string // "friend" 或 "group"
+	SubType     string // 群请求时为 "add"（加群申请）或 "invite"（邀请机器人入群）
+	UserID      int64  // 发起请求的 QQ 号
+	GroupID     int64  // 群请求的目标群号（好友请求时为 0）
+	Comment     string // 验证消息
+	Flag        string // 请求标识，处理请求时需原样回传
+}
+
+// MetaEvent 表示一个元事件（post_type 为 meta_event），如心跳、生命周期
+type MetaEvent struct {
+	MetaEventType string // "heartbeat" 或 "lifecycle"
+	SubType       string // 生命周期事件时为 "enable"、"disable" 或 "connect"
+	Interval      int64  // 心跳间隔（毫秒）
+	Online        bool   // 心跳状态中的 QQ 在线状态
+	Good          bool   // 心跳状态中的整体运行状态
+}
+
+// NoticeEvent 表示一个通知事件（post_type 为 notice），目前只关心群消息撤回
+type NoticeEvent struct {
+	NoticeType string // 如 "group_recall"
+	GroupID    int64
+	UserID     int64 // 被撤回消息的发送者
+	OperatorID int64 // 撤回操作者
+	MessageID  int64 // 被撤回的消息 ID
 }
@@ -0,0 +1,140 @@
+package deepseek
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"QQBot/internal/common"
+	"QQBot/internal/handler"
+	"QQBot/internal/storage"
+)
+
+const followUpClosingMaxRune = 8 // 结束语最多的字数，更长的消息即使以 "谢谢" 开头也可能是新问题
+
+// followUpClosings 表示对话结束的话，说了就关闭窗口、不再回复
+var followUpClosings = []string{"谢谢", "多谢", "谢啦", "好的", "好滴", "知道了", "明白了", "懂了", "没事了", "不用了", "拜拜", "再见"}
+
+// followUp 一个群友的追问窗口
+type followUp struct {
+	opened time.Time // 小牛最近一次回复此人的时间
+	until  time.Time
+}
+
+var (
+	followUpMu sync.Mutex
+	followUps  = make(map[int64]map[int64]*followUp) // 群号 -> QQ 号 -> 追问窗口
+)
+
+// OpenFollowUp 小牛在群里回复某人后打开（或重新计时）此人的追问窗口
+func OpenFollowUp(groupID int64, userID int64) {
+	if common.FollowUpWindow <= 0 || groupID == 0 || userID == 0 {
+		return
+	}
+	now := time.Now()
+
+	followUpMu.Lock()
+	defer followUpMu.Unlock()
+	if followUps[groupID] == nil {
+		followUps[groupID] = make(map[int64]*followUp)
+	}
+	followUps[groupID][userID] = &followUp{opened: now, until: now.Add(common.FollowUpWindow)}
+}
+
+// closeFollowUp 结束追问窗口
+func closeFollowUp(event common.QQEvent, reason string) {
+	followUpMu.Lock()
+	defer followUpMu.Unlock()
+	if _, ok := followUps[event.GroupID][event.UserID]; ok {
+		delete(followUps[event.GroupID], event.UserID)
+		if len(followUps[event.GroupID]) == 0 {
+			delete(followUps, event.GroupID)
+		}
+		log.Printf("[追问] 群%d 用户%d 窗口结束: %s", event.GroupID, event.UserID, reason)
+	}
+}
+
+// followUpMiddleware 每条群消息分发完后，按这条消息结束发送者已失效的追问窗口
+// 触发判断（EvaluateTrigger）会被多个处理器的 Match 调用，只读取窗口，窗口只在这里结束
+func followUpMiddleware() handler.Middleware {
+	return func(next handler.Next) handler.Next {
+		return func(ctx context.Context, event common.QQEvent) handler.Result {
+			result := next(ctx, event)
+			if event.MsgType == "group" {
+				if open, reason := followUpStatus(event); open && reason != "" {
+					closeFollowUp(event, reason)
+				}
+			}
+			return result
+		}
+	}
+}
+
+// inFollowUp 判断群消息是否处于发送者仍然有效的追问窗口内
+func inFollowUp(event common.QQEvent) bool {
+	if event.MsgType != "group" || event.Content == "" {
+		return false
+	}
+	open, reason := followUpStatus(event)
+	return open && reason == ""
+}
+
+// followUpStatus 判断发送者是否有追问窗口，以及这条消息是否让窗口结束（不修改状态）：
+// 超时、@了别人、转头和别人谈论小牛、说了结束语或期间其他人聊了太多（话题已转移）时结束
+func followUpStatus(event common.QQEvent) (open bool, endReason string) {
+	followUpMu.Lock()
+	f, ok := followUps[event.GroupID][event.UserID]
+	var opened, until time.Time
+	if ok {
+		opened, until = f.opened, f.until
+	}
+	followUpMu.Unlock()
+	if !ok {
+		return false, ""
+	}
+
+	switch {
+	case time.Now().After(until):
+		return true, "超时"
+	case event.AtType == common.AtOthers || event.AtType == common.AtMaster:
+		return true, "@了其他人"
+	case isClosingRemark(event.Content):
+		return true, "对话结束"
+	}
+	if _, talking := evaluateMention(event); talking {
+		return true, "在谈论小牛"
+	}
+	if interleavedSince(event.GroupID, event.UserID, opened) > common.FollowUpMaxInterleaved {
+		return true, "其他人聊了很多，话题已转移"
+	}
+	return true, ""
+}
+
+// interleavedSince 统计小牛回复此人之后，其他群友发了多少条消息
+func interleavedSince(groupID int64, userID int64, since time.Time) int {
+	count := 0
+	since = since.Truncate(time.Second) // 上下文中的时间精确到秒
+	for _, msg := range storage.RecentGroupContextMessages(groupID, storage.MaxGroupContextMessages) {
+		if msg.UserID == userID || msg.UserID == common.BotQQNumber {
+			continue
+		}
+		if t, err := time.Parse(time.RFC3339, msg.Time); err == nil && !t.Before(since) {
+			count++
+		}
+	}
+	return count
+}
+
+// isClosingRemark 判断消息是否是 "谢谢"、"好的" 之类的结束语
+func isClosingRemark(content string) bool {
+	content = strings.TrimFunc(content, func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsPunct(r) || unicode.IsSymbol(r)
+	})
+	if len([]rune(content)) > followUpClosingMaxRune {
+		return false
+	}
+	return hasPrefixAny(content, followUpClosings)
+}
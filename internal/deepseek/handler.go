@@ -1,27 +1,47 @@
 package deepseek
 
 import (
+	"context"
+	"errors"
 	"log"
 
 	"QQBot/internal/common"
+	"QQBot/internal/quota"
 )
 
 // HandleAIChat 处理 AI 对话请求
-func HandleAIChat(event common.QQEvent) {
+func HandleAIChat(ctx context.Context, event common.QQEvent) {
 	hint := getUserRoleHint(event.UserID)
 	log.Printf("[收到] <- 用户:%d 内容:%s", event.UserID, event.Content)
 
+	if extra, ok := injectionRoleHint(event); ok {
+		log.Printf("[注入] 群:%d 用户:%d 疑似注入，已提醒模型: %s", event.GroupID, event.UserID, event.Content)
+		hint += extra
+	}
+
+	// 需要 AI 判断是否在叫小牛时，判断本身也要先通过配额
+	classify := needsClassify(event)
+	if ok, notice := quota.Check(event); !ok {
+		if notice != "" && !classify { // 可能并没有在叫小牛，不提示
+			common.SendReply(event, notice)
+		}
+		return
+	}
+	if classify && !classifyAddressed(ctx, event) {
+		return
+	}
+
 	var answer string
 	var err error
 
 	switch {
 	case event.MsgType == "private":
-		answer, err = CallDeepSeekWithPrivateHistory(event.UserID, event.Content, hint)
+		answer, err = CallDeepSeekWithPrivateHistory(ctx, event.UserID, event.Content, hint)
 	case event.MsgType == "group" && event.GroupID > 0:
-		answer, err = CallDeepSeekWithGroupContext(event.GroupID, event.UserID, event.Content, hint)
+		answer, err = CallDeepSeekWithGroupContext(ctx, event.GroupID, event.UserID, event.Content, hint)
 	default:
 		// 其他消息类型，使用简单调用
-		answer, err = CallDeepSeekSimple(event.Content, hint)
+		answer, err = CallDeepSeekSimple(ctx, event.Content, hint)
 	}
 
 	if err != nil {
@@ -30,33 +50,9 @@ func HandleAIChat(event common.QQEvent) {
 	}
 
 	common.SendReply(event, answer)
-}
-
-// HandleAtMasterChat 处理群聊中@主人的情况
-func HandleAtMasterChat(event common.QQEvent) {
-	hint := "当前有人在群里@了你的主人（爸爸） niuf ，你需要转告给 niuf ，并总结一下群友@niuf的原因"
-
-	log.Printf("[@主人] <- 群:%d 用户:%d 内容:%s", event.GroupID, event.UserID, event.Content)
-
-	content := event.Content
-	if content == "" {
-		content = "@了你的主人（爸爸）"
-	}
-
-	answer, err := CallDeepSeekWithGroupContext(event.GroupID, event.UserID, content, hint)
-	if err != nil {
-		handleAIError(event, err)
-		return
-	}
-
-	// 构造私信事件，发送给主人
-	privateEvent := common.QQEvent{
-		MsgType: "private",
-		UserID:  common.MasterQQNumber,
-		GroupID: 0,
-		Content: answer,
+	if event.MsgType == "group" {
+		OpenFollowUp(event.GroupID, event.UserID) // 接下来一段时间此人不用再叫"小牛"
 	}
-	common.SendReply(privateEvent, answer)
 }
 
 // getUserRoleHint 根据用户ID获取角色提示
@@ -76,6 +72,11 @@ func getUserRoleHint(userID int64) string {
 
 // handleAIError 统一处理 AI 错误
 func handleAIError(event common.QQEvent, err error) {
+	if errors.Is(err, context.Canceled) {
+		// 任务被新消息取代，不需要回复
+		log.Printf("[AI] 已取消: 群:%d 用户:%d", event.GroupID, event.UserID)
+		return
+	}
 	log.Printf("[AI] 出错: %v", err)
 	common.SendReply(event, errorMessage)
 }
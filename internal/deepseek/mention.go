@@ -0,0 +1,302 @@
+package deepseek
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"QQBot/internal/common"
+	"QQBot/internal/handler"
+	"QQBot/internal/storage"
+)
+
+const (
+	mentionDataName  = "at_master_mentions" // 待转告的 @主人 消息的存储名称
+	mentionTick      = 30 * time.Second     // 检查汇总窗口和免打扰时段的周期
+	mentionBufferMax = 30                   // 每个群最多缓存的 @ 条数，超出的只计数
+	mentionListMax   = 10                   // 汇总中最多列出的原消息条数
+	mentionContextN  = 20                   // 总结 @ 原因时附带的群聊上下文条数
+
+	mentionHint = "下面是群里@你爸爸 niuf 的消息，你需要转告给爸爸：说明有谁找他、分别为什么，结合群聊上下文判断原因，语气亲近，不超过 150 字"
+)
+
+// mention 一条 @主人 的群消息
+type mention struct {
+	GroupID   int64     `json:"group_id"`
+	UserID    int64     `json:"user_id"`
+	Nickname  string    `json:"nickname"`
+	MessageID int64     `json:"message_id"`
+	Content   string    `json:"content"`
+	Time      time.Time `json:"time"`
+}
+
+// mentionState 待转告的 @ 和免打扰期间暂存的汇总（持久化，重启后继续转告）
+type mentionState struct {
+	Pending map[int64][]mention `json:"pending"` // 群号 -> 窗口内的 @
+	Dropped map[int64]int       `json:"dropped"` // 群号 -> 超出缓存上限未记录的条数
+	Held    []string            `json:"held"`    // 免打扰期间生成的汇总，时段结束后一并发送
+}
+
+var (
+	mentionMu    sync.Mutex
+	mentions     *mentionState
+	flushing     = make(map[int64]bool) // 正在生成汇总的群，避免重复提交
+	mentionsOnce sync.Once
+	mentionLoop  *common.TickLoop
+)
+
+// StartMentionDigests 启动 @主人 汇总的定时检查（程序启动时调用一次）
+func StartMentionDigests() {
+	mentionsOnce.Do(func() {
+		mentionLoop = common.StartTickLoop(mentionTick, false, flushDueMentions)
+	})
+}
+
+// StopMentionDigests 停止 @主人 汇总的定时检查并等待正在执行的一轮结束（程序退出前调用）
+// 尚未转告的 @ 已持久化，重启后继续转告
+func StopMentionDigests() {
+	mentionLoop.Stop()
+}
+
+// HandleAtMasterChat 处理群聊中@主人的情况
+// 同一群的 @ 在 AtMasterWindow 内合并为一条汇总私聊给主人；包含紧急关键词的立即转告
+func HandleAtMasterChat(_ context.Context, event common.QQEvent) {
+	log.Printf("[@主人] <- 群:%d 用户:%d 内容:%s", event.GroupID, event.UserID, event.Content)
+
+	m := mention{
+		GroupID:   event.GroupID,
+		UserID:    event.UserID,
+		Nickname:  storage.GetNickname(event.GroupID, event.UserID),
+		MessageID: event.MessageID,
+		Content:   event.Content,
+		Time:      time.Now(),
+	}
+	if m.Content == "" {
+		m.Content = "@了你"
+	}
+
+	if isUrgentMention(m.Content) {
+		log.Printf("[@主人] 群:%d 用户:%d 包含紧急关键词，立即转告", event.GroupID, event.UserID)
+		sendToMaster(fmt.Sprintf("🚨 群%d 有紧急的@：\n%s", m.GroupID, formatMention(m)))
+		return
+	}
+
+	mentionMu.Lock()
+	loadMentionsLocked()
+	if len(mentions.Pending[m.GroupID]) >= mentionBufferMax {
+		mentions.Dropped[m.GroupID]++
+	} else {
+		mentions.Pending[m.GroupID] = append(mentions.Pending[m.GroupID], m)
+	}
+	saveMentionsLocked()
+	mentionMu.Unlock()
+
+	if common.AtMasterWindow <= 0 {
+		flushDueMentions(time.Now())
+	}
+}
+
+// flushDueMentions 为窗口已到期的群生成汇总，并在免打扰时段结束后发送暂存的汇总
+func flushDueMentions(now time.Time) {
+	mentionMu.Lock()
+	loadMentionsLocked()
+
+	var due []int64
+	for groupID, list := range mentions.Pending {
+		if len(list) > 0 && !flushing[groupID] && now.Sub(list[0].Time) >= common.AtMasterWindow {
+			flushing[groupID] = true
+			due = append(due, groupID)
+		}
+	}
+
+	var held []string
+	if len(mentions.Held) > 0 && !common.InQuietHours(now) {
+		held = mentions.Held
+		mentions.Held = nil
+		saveMentionsLocked()
+	}
+	mentionMu.Unlock()
+
+	if len(held) > 0 {
+		sendToMaster("爸爸，免打扰期间有人找你：\n\n" + strings.Join(held, "\n\n"))
+	}
+	for _, groupID := range due {
+		submitMentionDigest(groupID)
+	}
+}
+
+// submitMentionDigest 提交生成某个群 @ 汇总的任务，成功转告后才从待转告列表中移除
+func submitMentionDigest(groupID int64) {
+	mentionMu.Lock()
+	list := append([]mention(nil), mentions.Pending[groupID]...)
+	dropped := mentions.Dropped[groupID]
+	mentionMu.Unlock()
+
+	event := common.QQEvent{MsgType: "group", GroupID: groupID}
+	accepted := handler.Submit(context.Background(), "at_master", event, "at_master", "", func(ctx context.Context) {
+		digest := buildMentionDigest(ctx, groupID, list, dropped)
+
+		mentionMu.Lock()
+		defer mentionMu.Unlock()
+		delete(flushing, groupID)
+		if digest == "" {
+			return
+		}
+		// 生成汇总期间新到的 @ 留到下一个窗口
+		remaining := mentions.Pending[groupID][len(list):]
+		if len(remaining) == 0 {
+			delete(mentions.Pending, groupID)
+		} else {
+			mentions.Pending[groupID] = remaining
+		}
+		mentions.Dropped[groupID] -= dropped
+		if mentions.Dropped[groupID] <= 0 {
+			delete(mentions.Dropped, groupID)
+		}
+
+		if common.InQuietHours(time.Now()) {
+			log.Printf("[@主人] 群:%d 免打扰时段，汇总暂存", groupID)
+			mentions.Held = append(mentions.Held, digest)
+		} else {
+			go sendToMaster(digest)
+		}
+		saveMentionsLocked()
+	})
+
+	if !accepted {
+		mentionMu.Lock()
+		delete(flushing, groupID)
+		mentionMu.Unlock()
+	}
+}
+
+// buildMentionDigest 生成一个群的 @ 汇总：AI 总结原因 + 原消息列表（AI 失败时只有原消息）
+// 任务被取消（如正在退出）时返回空字符串，保留待转告的 @
+func buildMentionDigest(ctx context.Context, groupID int64, list []mention, dropped int) string {
+	var raw, prompt strings.Builder
+	for i, m := range list {
+		if i >= mentionListMax {
+			dropped += len(list) - i
+			break
+		}
+		raw.WriteString("\n" + formatMention(m))
+		prompt.WriteString("\n" + formatMentionForAI(m))
+	}
+	if dropped > 0 {
+		raw.WriteString(fmt.Sprintf("\n……另有 %d 条", dropped))
+		prompt.WriteString(fmt.Sprintf("\n……另有 %d 条", dropped))
+	}
+
+	var contextLines strings.Builder
+	for _, msg := range storage.RecentGroupContextMessages(groupID, mentionContextN) {
+		contextLines.WriteString(storage.FormatGroupMessage(groupID, msg.UserID, msg.Content) + "\n")
+	}
+	messages := []map[string]string{
+		{"role": "system", "content": buildSystemMessage(true, mentionHint)},
+		{"role": "user", "content": "群聊消息：\n" + contextLines.String()},
+		{"role": "user", "content": "@爸爸的消息：" + prompt.String()},
+	}
+
+	summary, err := callPersonaAPI(ctx, 0, groupID, messages)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ""
+		}
+		log.Printf("[@主人] 群:%d 总结失败，只转告原消息: %v", groupID, err)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("📮 群%d 有 %d 条@你", groupID, len(list)+dropped))
+	if summary != "" {
+		sb.WriteString("\n" + summary)
+	}
+	sb.WriteString("\n\n原消息：" + raw.String())
+	return sb.String()
+}
+
+// formatMentionForAI 按群聊消息格式拼出交给 AI 的一行，昵称和内容经过转义，无法伪造带标签的发言
+func formatMentionForAI(m mention) string {
+	return fmt.Sprintf("[%s]%s", m.Time.Format("01-02 15:04"), storage.FormatGroupMessageAs(m.UserID, m.Nickname, m.Content))
+}
+
+// formatMention 格式化一条 @：时间、发送者、内容、消息 ID（便于在群里定位原消息），原样转告给主人
+func formatMention(m mention) string {
+	line := fmt.Sprintf("[%s] %s(%d)：%s", m.Time.Format("01-02 15:04"), common.EscapeCQText(m.Nickname), m.UserID, common.EscapeCQText(m.Content))
+	if m.MessageID != 0 {
+		line += fmt.Sprintf("（消息ID %d）", m.MessageID)
+	}
+	return line
+}
+
+// urgentNegations 紧急关键词前出现这些字时不算紧急，如 "不紧急"、"没急事"
+var urgentNegations = []string{"不", "没", "别", "无"}
+
+// isUrgentMention 判断 @ 是否包含紧急关键词（排除被否定的说法）
+func isUrgentMention(content string) bool {
+	for _, keyword := range common.AtMasterUrgentKeywords {
+		if keyword == "" {
+			continue
+		}
+		for rest := content; ; {
+			i := strings.Index(rest, keyword)
+			if i < 0 {
+				break
+			}
+			if !hasNegationSuffix(rest[:i]) {
+				return true
+			}
+			rest = rest[i+len(keyword):]
+		}
+	}
+	return false
+}
+
+// hasNegationSuffix 判断文字是否以否定词结尾（允许中间隔一个"太"、"很"之类的程度词）
+func hasNegationSuffix(s string) bool {
+	s = strings.TrimRight(s, " ")
+	for _, degree := range []string{"太", "很", "那么", "怎么"} {
+		if trimmed := strings.TrimSuffix(s, degree); trimmed != s {
+			s = trimmed
+			break
+		}
+	}
+	for _, neg := range urgentNegations {
+		if strings.HasSuffix(s, neg) {
+			return true
+		}
+	}
+	return false
+}
+
+// sendToMaster 私聊发送给主人
+func sendToMaster(text string) {
+	if common.MasterQQNumber == 0 {
+		return
+	}
+	common.SendPrivateMessage(common.MasterQQNumber, text)
+}
+
+// loadMentionsLocked 首次使用时从存储加载
+func loadMentionsLocked() {
+	if mentions != nil {
+		return
+	}
+	mentions = &mentionState{}
+	storage.LoadData(mentionDataName, mentions)
+	if mentions.Pending == nil {
+		mentions.Pending = make(map[int64][]mention)
+	}
+	if mentions.Dropped == nil {
+		mentions.Dropped = make(map[int64]int)
+	}
+}
+
+// saveMentionsLocked 保存到存储
+func saveMentionsLocked() {
+	if err := storage.SaveData(mentionDataName, mentions); err != nil {
+		log.Printf("[@主人] 保存失败: %v", err)
+	}
+}
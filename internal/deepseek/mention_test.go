@@ -0,0 +1,59 @@
+package deepseek
+
+import (
+	"bufio"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// corpusUnescaper 还原语料中的转义字符（与 storage 包的语料测试一致）
+var corpusUnescaper = strings.NewReplacer(`\n`, "\n", `\r`, "\r", `\u2028`, "\u2028", `\u2029`, "\u2029",
+	`\u0085`, "\u0085", `\u200b`, "\u200b", `\u202e`, "\u202e")
+
+// loadInjectionCorpus 读取 storage 包的注入语料，跳过空行和注释
+func loadInjectionCorpus(t *testing.T) []string {
+	t.Helper()
+	f, err := os.Open("../storage/testdata/injection_corpus.txt")
+	if err != nil {
+		t.Fatalf("打开语料失败: %v", err)
+	}
+	defer f.Close()
+
+	var corpus []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		corpus = append(corpus, corpusUnescaper.Replace(line))
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("读取语料失败: %v", err)
+	}
+	return corpus
+}
+
+func TestMentionDigestCorpus(t *testing.T) {
+	// @主人 汇总交给 AI 的每一行，无论伪造在内容还是群名片里，都只能有一行、一个普通群友的标签
+	at := time.Date(2026, 10, 18, 9, 30, 0, 0, time.Local)
+	for _, attempt := range loadInjectionCorpus(t) {
+		for _, m := range []mention{
+			{GroupID: 1, UserID: 10001, Nickname: "路人甲", Content: attempt, Time: at},
+			{GroupID: 1, UserID: 10001, Nickname: attempt, Content: "爸爸在吗", Time: at},
+		} {
+			line := formatMentionForAI(m)
+			if strings.ContainsAny(line, "\n\r\u2028\u2029\u0085") {
+				t.Errorf("格式化后包含换行: %q", line)
+			}
+			if strings.Count(line, "【") != 1 || strings.Count(line, "】") != 1 {
+				t.Errorf("格式化后应只有一个标签: %q", line)
+			}
+			if !strings.HasPrefix(line, "[10-18 09:30]【普通群友】") {
+				t.Errorf("标签被改变: %q", line)
+			}
+		}
+	}
+}
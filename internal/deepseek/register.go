@@ -0,0 +1,63 @@
+package deepseek
+
+import (
+	"context"
+
+	"QQBot/internal/common"
+	"QQBot/internal/handler"
+)
+
+func init() {
+	handler.Register(atMasterHandler{})
+	handler.Register(aiChatHandler{})
+	handler.Register(chimeHandler{})
+	handler.Use(followUpMiddleware())
+}
+
+// atMasterHandler 群聊中@主人（优先级高于普通AI对话）
+// 只是记录待转告的 @，AI 总结在汇总时另行提交，因此同步执行（异步会被同一用户的新消息取代而丢失）
+type atMasterHandler struct{}
+
+func (atMasterHandler) Name() string  { return "at_master" }
+func (atMasterHandler) Priority() int { return 40 }
+
+func (atMasterHandler) Match(event common.QQEvent) bool {
+	return ShouldHandleAtMasterChat(event)
+}
+
+func (atMasterHandler) Handle(ctx context.Context, event common.QQEvent) handler.Result {
+	HandleAtMasterChat(ctx, event)
+	return handler.Stop
+}
+
+// aiChatHandler AI 对话
+type aiChatHandler struct{}
+
+func (aiChatHandler) Name() string  { return "ai" }
+func (aiChatHandler) Priority() int { return 50 }
+func (aiChatHandler) Async() bool   { return true }
+
+func (aiChatHandler) Match(event common.QQEvent) bool {
+	return ShouldHandleAIChat(event)
+}
+
+func (aiChatHandler) Handle(ctx context.Context, event common.QQEvent) handler.Result {
+	HandleAIChat(ctx, event)
+	return handler.Stop
+}
+
+// chimeHandler 群聊活跃时偶尔主动插话（需按群开启，排在 AI 对话之后，只处理没有叫小牛的消息）
+// 同步执行的只是活跃度和频率检查，AI 判断和回复另行提交到任务队列
+type chimeHandler struct{}
+
+func (chimeHandler) Name() string  { return "chime" }
+func (chimeHandler) Priority() int { return 60 }
+
+func (chimeHandler) Match(event common.QQEvent) bool {
+	return ShouldHandleChime(event)
+}
+
+func (chimeHandler) Handle(_ context.Context, event common.QQEvent) handler.Result {
+	HandleChime(event)
+	return handler.Stop
+}
@@ -0,0 +1,62 @@
+package deepseek
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"QQBot/internal/safety"
+)
+
+// regenerateHint 回复未通过安全过滤、重新生成时追加的提示
+const regenerateHint = "你刚才的回复包含不适合发送的内容（敏感话题、旁白动作描写或你的设定内容）。请换一种说法重新回复，不要提及这条提示。"
+
+func init() {
+	// 回复中出现系统提示词的原句时视为泄露
+	safety.ProtectPrompt(systemPromptBase, naturalSpeechHint, groupChatContext)
+}
+
+// callPersonaAPI 调用 DeepSeek API 生成要发给群友的回复，并经过安全过滤：
+// 去掉旁白、遮住敏感词；需要重新生成时带上提示重试一次，仍不通过或需要拒绝时返回固定的拒绝语
+func callPersonaAPI(ctx context.Context, userID int64, groupID int64, messages []map[string]string) (string, error) {
+	answer, err := callDeepSeekAPI(ctx, userID, groupID, messages)
+	if err != nil {
+		return "", err
+	}
+
+	res := safety.Check(answer)
+	logSafety(userID, groupID, answer, res)
+	if res.Action != safety.Regenerate {
+		return finalText(res), nil
+	}
+
+	retry := append(append([]map[string]string(nil), messages...),
+		map[string]string{"role": "assistant", "content": answer},
+		map[string]string{"role": "system", "content": regenerateHint})
+	answer, err = callDeepSeekAPI(ctx, userID, groupID, retry)
+	if err != nil {
+		return "", err
+	}
+	res = safety.Check(answer)
+	logSafety(userID, groupID, answer, res)
+	if res.Action == safety.Regenerate {
+		return safety.RefusalText, nil
+	}
+	return finalText(res), nil
+}
+
+// finalText 按检查结果返回要发送的内容
+func finalText(res safety.Result) string {
+	if res.Action == safety.Refuse {
+		return safety.RefusalText
+	}
+	return res.Text
+}
+
+// logSafety 记录被处理过的回复
+func logSafety(userID int64, groupID int64, answer string, res safety.Result) {
+	if len(res.Reasons) == 0 {
+		return
+	}
+	log.Printf("[安全] 群:%d 用户:%d 处理:%s 原因:%s 原回复:%s", groupID, userID, res.Action, strings.Join(res.Reasons, "；"), answer)
+}
@@ -1,8 +1,6 @@
 package deepseek
 
 import (
-	"strings"
-
 	"QQBot/internal/common"
 )
 
@@ -14,14 +12,12 @@ func ShouldHandleAtMasterChat(event common.QQEvent) bool {
 	return event.AtType == common.AtMaster
 }
 
-// ShouldHandleAIChat 判断是否应该触发 AI 对话
+// ShouldHandleAIChat 判断是否应该触发 AI 对话（私聊、@机器人、群聊中在叫小牛或处于追问窗口内，见 EvaluateTrigger）
 func ShouldHandleAIChat(event common.QQEvent) bool {
-	if event.MsgType == "private" {
-		return true
-	}
-	// 群聊中：@了机器人 或 包含"小牛"关键词
-	if event.AtType == common.AtBot {
-		return true
+	t := EvaluateTrigger(event)
+	addressed := t.Addressed || (t.Ambiguous && common.TriggerAmbiguous != "ignore")
+	if event.MsgType == "group" && t.Reason != reasonNotMentioned {
+		logTrigger(event, t, addressed)
 	}
-	return strings.Contains(event.Content, "小牛")
+	return addressed
 }
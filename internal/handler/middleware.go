@@ -0,0 +1,189 @@
+package handler
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+
+	"QQBot/internal/common"
+)
+
+// Next 中间件包裹的处理函数
+type Next func(ctx context.Context, event common.QQEvent) Result
+
+// Middleware 中间件，在 next 前后插入横切逻辑（黑名单、限流、日志、恢复等）
+// 返回 Stop 而不调用 next 即可拦截事件
+type Middleware func(next Next) Next
+
+type ctxKey int
+
+const (
+	traceIDKey ctxKey = iota
+	handlerNameKey
+)
+
+// slowThreshold 耗时超过此值的处理会打印日志
+const slowThreshold = 3 * time.Second
+
+var (
+	middlewareMu       sync.RWMutex
+	eventMiddlewares   []Middleware // 包裹整个分发过程
+	handlerMiddlewares []Middleware // 包裹每个处理器的执行（含异步 goroutine）
+)
+
+func init() {
+	Use(Trace(), Recover(), Timing())
+	UseHandler(Recover(), Timing())
+}
+
+// Use 注册事件级中间件，包裹整个 Dispatch，先注册的在外层
+func Use(mws ...Middleware) {
+	middlewareMu.Lock()
+	defer middlewareMu.Unlock()
+	eventMiddlewares = append(eventMiddlewares, mws...)
+}
+
+// UseHandler 注册处理器级中间件，包裹每个处理器的 Handle，先注册的在外层
+func UseHandler(mws ...Middleware) {
+	middlewareMu.Lock()
+	defer middlewareMu.Unlock()
+	handlerMiddlewares = append(handlerMiddlewares, mws...)
+}
+
+// chain 按注册顺序把 scope 中的中间件包裹在 final 外层
+func chain(scope *[]Middleware, final Next) Next {
+	middlewareMu.RLock()
+	mws := *scope
+	middlewareMu.RUnlock()
+	for i := len(mws) - 1; i >= 0; i-- {
+		final = mws[i](final)
+	}
+	return final
+}
+
+// TraceID 获取当前事件的追踪 ID
+func TraceID(ctx context.Context) string {
+	id, _ := ctx.Value(traceIDKey).(string)
+	return id
+}
+
+// HandlerName 获取当前正在执行的处理器名（事件级中间件中为 "dispatch"）
+func HandlerName(ctx context.Context) string {
+	if name, ok := ctx.Value(handlerNameKey).(string); ok {
+		return name
+	}
+	return "dispatch"
+}
+
+// Trace 为每个事件生成追踪 ID，便于串联同一事件在各处理器中的日志
+func Trace() Middleware {
+	return func(next Next) Next {
+		return func(ctx context.Context, event common.QQEvent) Result {
+			if TraceID(ctx) == "" {
+				ctx = context.WithValue(ctx, traceIDKey, newTraceID())
+			}
+			return next(ctx, event)
+		}
+	}
+}
+
+// Recover 捕获 panic，避免单个处理器崩溃导致整个进程退出
+func Recover() Middleware {
+	return func(next Next) Next {
+		return func(ctx context.Context, event common.QQEvent) (result Result) {
+			defer func() {
+				if r := recover(); r != nil {
+					log.Printf("[panic] trace=%s 处理器:%s 群:%d 用户:%d: %v\n%s",
+						TraceID(ctx), HandlerName(ctx), event.GroupID, event.UserID, r, debug.Stack())
+					recordPanic(HandlerName(ctx))
+					result = Stop
+				}
+			}()
+			return next(ctx, event)
+		}
+	}
+}
+
+// Timing 统计处理耗时，超过 slowThreshold 时打印日志
+func Timing() Middleware {
+	return func(next Next) Next {
+		return func(ctx context.Context, event common.QQEvent) Result {
+			start := time.Now()
+			result := next(ctx, event)
+			elapsed := time.Since(start)
+
+			recordTiming(HandlerName(ctx), elapsed)
+			if elapsed > slowThreshold {
+				log.Printf("[耗时] trace=%s 处理器:%s 用时 %v", TraceID(ctx), HandlerName(ctx), elapsed.Round(time.Millisecond))
+			}
+			return result
+		}
+	}
+}
+
+// Stat 处理器运行统计
+type Stat struct {
+	Name   string
+	Count  int64
+	Panics int64
+	Total  time.Duration
+	Max    time.Duration
+}
+
+var (
+	statsMu sync.Mutex
+	stats   = make(map[string]*Stat)
+)
+
+// Stats 返回各处理器的运行统计，按名称排序
+func Stats() []Stat {
+	statsMu.Lock()
+	defer statsMu.Unlock()
+
+	list := make([]Stat, 0, len(stats))
+	for _, s := range stats {
+		list = append(list, *s)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+func statOf(name string) *Stat {
+	s, ok := stats[name]
+	if !ok {
+		s = &Stat{Name: name}
+		stats[name] = s
+	}
+	return s
+}
+
+func recordTiming(name string, elapsed time.Duration) {
+	statsMu.Lock()
+	defer statsMu.Unlock()
+	s := statOf(name)
+	s.Count++
+	s.Total += elapsed
+	if elapsed > s.Max {
+		s.Max = elapsed
+	}
+}
+
+func recordPanic(name string) {
+	statsMu.Lock()
+	defer statsMu.Unlock()
+	statOf(name).Panics++
+}
+
+// newTraceID 生成 8 位十六进制追踪 ID
+func newTraceID() string {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "00000000"
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,162 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+
+	"QQBot/internal/common"
+	"QQBot/internal/worker"
+)
+
+// Result 处理结果，决定是否继续交给后续处理器
+type Result int
+
+const (
+	Stop     Result = iota // 已处理，不再交给后续处理器
+	Continue               // 继续交给后续处理器
+)
+
+// Handler 消息处理器
+// 各模块在 init() 中调用 Register 注册自己的处理器，dispatch 按优先级依次尝试
+type Handler interface {
+	Name() string                                            // 唯一名称，用于配置优先级和按群开关
+	Priority() int                                           // 默认优先级，数值越小越先执行
+	Match(event common.QQEvent) bool                         // 是否处理该消息
+	Handle(ctx context.Context, event common.QQEvent) Result // 处理消息
+}
+
+// AsyncHandler 可选接口：Async 返回 true 的处理器提交到任务队列中执行
+// 同一会话（私聊用户/群）的任务按顺序执行，群聊中同一用户的新消息会取代其尚未完成的旧任务
+// 异步处理器匹配后视为 Stop（耗时操作如 AI 调用不阻塞消息接收）
+type AsyncHandler interface {
+	Async() bool
+}
+
+var (
+	registryMu sync.RWMutex
+	handlers   []Handler // 按优先级排序
+)
+
+// Register 注册处理器，名称重复时后注册的会被忽略
+func Register(h Handler) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	for _, existing := range handlers {
+		if existing.Name() == h.Name() {
+			log.Printf("[处理器] 名称重复，忽略: %s", h.Name())
+			return
+		}
+	}
+
+	handlers = append(handlers, h)
+	sort.SliceStable(handlers, func(i, j int) bool {
+		return priorityOf(handlers[i]) < priorityOf(handlers[j])
+	})
+}
+
+// Handlers 返回按执行顺序排列的所有处理器
+func Handlers() []Handler {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	return append([]Handler(nil), handlers...)
+}
+
+// Lookup 按名称查找处理器
+func Lookup(name string) (Handler, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	for _, h := range handlers {
+		if h.Name() == name {
+			return h, true
+		}
+	}
+	return nil, false
+}
+
+// Dispatch 将消息依次交给匹配的处理器，直到某个处理器返回 Stop
+// 整个分发过程由事件级中间件包裹，每个处理器的执行由处理器级中间件包裹
+func Dispatch(event common.QQEvent) {
+	chain(&eventMiddlewares, dispatchHandlers)(context.Background(), event)
+}
+
+// dispatchHandlers 按优先级执行匹配的处理器
+func dispatchHandlers(ctx context.Context, event common.QQEvent) Result {
+	for _, h := range Handlers() {
+		if !IsEnabled(event.GroupID, h.Name()) || !h.Match(event) {
+			continue
+		}
+
+		hctx := context.WithValue(ctx, handlerNameKey, h.Name())
+		run := chain(&handlerMiddlewares, h.Handle)
+
+		result := Stop
+		if isAsync(h) {
+			key, supersede := queueKeys(h.Name(), event)
+			worker.Submit(hctx, worker.Job{
+				Key:       key,
+				Supersede: supersede,
+				Run:       func(ctx context.Context) { run(ctx, event) },
+			})
+		} else {
+			result = run(hctx, event)
+		}
+
+		if isPassthrough(h.Name()) {
+			result = Continue
+		}
+		if result == Stop {
+			return Stop
+		}
+	}
+	return Continue
+}
+
+// Submit 将耗时操作提交到任务队列，执行时同样经过处理器级中间件（panic 恢复、耗时统计）
+// 供同步执行的处理器（如指令）发起 AI 调用等操作，避免阻塞消息接收
+func Submit(ctx context.Context, name string, event common.QQEvent, key string, supersede string, fn func(ctx context.Context)) bool {
+	hctx := context.WithValue(ctx, handlerNameKey, name)
+	run := chain(&handlerMiddlewares, func(ctx context.Context, _ common.QQEvent) Result {
+		fn(ctx)
+		return Stop
+	})
+	return worker.Submit(hctx, worker.Job{
+		Key:       key,
+		Supersede: supersede,
+		Run:       func(ctx context.Context) { run(ctx, event) },
+	})
+}
+
+// queueKeys 异步任务的队列键：私聊按用户串行，群聊按群串行
+// 群聊中同一处理器、同一用户的新任务取代旧任务（群聊上下文已包含旧消息，只需回复最新的）
+func queueKeys(name string, event common.QQEvent) (key string, supersede string) {
+	if event.GroupID == 0 {
+		return fmt.Sprintf("private:%d", event.UserID), ""
+	}
+	return fmt.Sprintf("group:%d", event.GroupID), fmt.Sprintf("%s:group:%d:%d", name, event.GroupID, event.UserID)
+}
+
+// priorityOf 获取处理器的实际优先级（配置优先）
+func priorityOf(h Handler) int {
+	if p, ok := common.HandlerPriorities[h.Name()]; ok {
+		return p
+	}
+	return h.Priority()
+}
+
+func isAsync(h Handler) bool {
+	a, ok := h.(AsyncHandler)
+	return ok && a.Async()
+}
+
+func isPassthrough(name string) bool {
+	for _, n := range common.HandlerPassthrough {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
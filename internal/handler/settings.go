@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"log"
+	"sync"
+
+	"QQBot/internal/storage"
+)
+
+const settingsDataName = "handler_settings" // 按群开关处理器的存储名称
+
+var (
+	settingsMu     sync.Mutex
+	disabledGroups map[int64]map[string]bool // 群号 -> 已关闭的处理器名
+)
+
+// IsEnabled 判断处理器在指定群是否启用（私聊 groupID=0 始终启用）
+func IsEnabled(groupID int64, name string) bool {
+	if groupID == 0 {
+		return true
+	}
+	settingsMu.Lock()
+	loadSettingsLocked()
+	disabled := disabledGroups[groupID][name]
+	settingsMu.Unlock()
+	return !disabled
+}
+
+// SetEnabled 在指定群开启或关闭处理器，并保存
+func SetEnabled(groupID int64, name string, enabled bool) {
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+	loadSettingsLocked()
+
+	if enabled {
+		delete(disabledGroups[groupID], name)
+		if len(disabledGroups[groupID]) == 0 {
+			delete(disabledGroups, groupID)
+		}
+	} else {
+		if disabledGroups[groupID] == nil {
+			disabledGroups[groupID] = make(map[string]bool)
+		}
+		disabledGroups[groupID][name] = true
+	}
+	saveSettingsLocked()
+}
+
+// loadSettingsLocked 首次使用时加载按群开关配置
+func loadSettingsLocked() {
+	if disabledGroups != nil {
+		return
+	}
+	disabledGroups = make(map[int64]map[string]bool)
+
+	var saved map[int64][]string
+	if !storage.LoadData(settingsDataName, &saved) {
+		// 不存在是正常的
+		return
+	}
+	for groupID, names := range saved {
+		disabledGroups[groupID] = make(map[string]bool)
+		for _, name := range names {
+			disabledGroups[groupID][name] = true
+		}
+	}
+}
+
+// saveSettingsLocked 保存按群开关配置
+func saveSettingsLocked() {
+	saved := make(map[int64][]string, len(disabledGroups))
+	for groupID, names := range disabledGroups {
+		for name := range names {
+			saved[groupID] = append(saved[groupID], name)
+		}
+	}
+	if err := storage.SaveData(settingsDataName, saved); err != nil {
+		log.Printf("[处理器] 保存开关配置失败: %v", err)
+	}
+}
@@ -1,18 +1,17 @@
 package local
 
 import (
-	"log"
-
-	"QQBot/internal/common"
+	"QQBot/internal/command"
 )
 
-// HandleLocalCommand 处理本地命令（以 niuf 开头的消息）
-func HandleLocalCommand(event common.QQEvent) {
-	log.Printf("[本地] 收到指令: %s", event.Content)
-	common.SendReply(event, "1")
-}
-
-// ShouldHandleLocalCommand 判断是否应该处理本地命令
-func ShouldHandleLocalCommand(content string) bool {
-	return content == "小牛"
+func init() {
+	command.Register(&command.Command{
+		Name:        "ping",
+		Aliases:     []string{"在吗"},
+		Description: "看看小牛在不在",
+		Run: func(ctx *command.Context) error {
+			ctx.Reply("在的～")
+			return nil
+		},
+	})
 }
@@ -0,0 +1,226 @@
+package local
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"QQBot/internal/command"
+	"QQBot/internal/common"
+	"QQBot/internal/handler"
+	"QQBot/internal/storage"
+)
+
+const (
+	forgetPhrase       = "忘掉我们的聊天" // 普通用户清除自己私聊历史的口令
+	defaultShowCount   = 10        // 查看历史默认条数
+	showContentMaxRune = 100       // 查看历史时单条消息最多显示的字符数
+)
+
+func init() {
+	userArg := []command.Arg{{Name: "QQ", Type: command.ArgQQ}}
+	groupArg := []command.Arg{{Name: "群号", Type: command.ArgQQ, Optional: true}}
+
+	command.Register(&command.Command{
+		Name:        "history",
+		Aliases:     []string{"历史"},
+		Description: "管理私聊历史和群聊上下文",
+		Permission:  command.PermAdmin,
+		Subcommands: []*command.Command{
+			{
+				Name: "clear", Aliases: []string{"清除"}, Description: "清除记录",
+				Subcommands: []*command.Command{
+					{Name: "user", Aliases: []string{"私聊"}, Permission: command.PermMaster, Args: userArg, Run: runClearUser},
+					{Name: "group", Aliases: []string{"群聊"}, Args: groupArg, Run: runClearGroup},
+				},
+			},
+			{
+				Name: "show", Aliases: []string{"查看"}, Description: "查看最近的记录",
+				Subcommands: []*command.Command{
+					{Name: "user", Aliases: []string{"私聊"}, Permission: command.PermMaster,
+						Args: append(userArg, command.Arg{Name: "条数", Type: command.ArgInt, Optional: true}), Run: runShowUser},
+					{Name: "group", Aliases: []string{"群聊"},
+						Args: append(groupArg, command.Arg{Name: "条数", Type: command.ArgInt, Optional: true}), Run: runShowGroup},
+				},
+			},
+			{
+				Name: "export", Aliases: []string{"导出"}, Description: "导出记录文件",
+				Subcommands: []*command.Command{
+					{Name: "user", Aliases: []string{"私聊"}, Permission: command.PermMaster, Args: userArg, Run: runExportUser},
+					{Name: "group", Aliases: []string{"群聊"}, Args: groupArg, Run: runExportGroup},
+				},
+			},
+		},
+	})
+
+	command.Register(&command.Command{
+		Name:        "forget",
+		Aliases:     []string{forgetPhrase},
+		Description: "清除你和小牛的私聊记录",
+		PrivateOnly: true,
+		Run: func(ctx *command.Context) error {
+			forgetPrivateHistory(ctx.Event)
+			return nil
+		},
+	})
+	handler.Register(forgetHandler{})
+}
+
+// forgetHandler 私聊中直接发送“忘掉我们的聊天”（不带指令前缀）时清除自己的私聊历史
+type forgetHandler struct{}
+
+func (forgetHandler) Name() string  { return "forget" }
+func (forgetHandler) Priority() int { return 25 }
+
+func (forgetHandler) Match(event common.QQEvent) bool {
+	return event.MsgType == "private" && strings.TrimRight(event.Content, "。.！!～~") == forgetPhrase
+}
+
+func (forgetHandler) Handle(_ context.Context, event common.QQEvent) handler.Result {
+	forgetPrivateHistory(event)
+	return handler.Stop
+}
+
+// forgetPrivateHistory 清除发送者自己的私聊历史
+func forgetPrivateHistory(event common.QQEvent) {
+	if storage.ClearConversation(event.UserID) == 0 {
+		common.SendReply(event, "我们之间还没有聊天记录哦～")
+		return
+	}
+	common.SendReply(event, "好的，小牛已经把我们之前的聊天都忘掉啦，我们重新开始吧～")
+}
+
+// targetGroup 解析要操作的群：未指定时为当前群；管理员只能操作当前群，主人可操作任意群
+func targetGroup(ctx *command.Context) (int64, error) {
+	groupID := ctx.Event.GroupID
+	if ctx.Has("群号") {
+		groupID = ctx.QQ("群号")
+	}
+	if groupID == 0 {
+		return 0, command.Usagef("私聊中需要指定群号")
+	}
+	if groupID != ctx.Event.GroupID && ctx.Level < command.PermMaster {
+		return 0, fmt.Errorf("只能管理当前所在的群")
+	}
+	return groupID, nil
+}
+
+func runClearUser(ctx *command.Context) error {
+	count := storage.ClearConversation(ctx.QQ("QQ"))
+	ctx.Reply(fmt.Sprintf("已清除 %d 的私聊历史，共 %d 条", ctx.QQ("QQ"), count))
+	return nil
+}
+
+func runClearGroup(ctx *command.Context) error {
+	groupID, err := targetGroup(ctx)
+	if err != nil {
+		return err
+	}
+	count := storage.ClearGroupContext(groupID)
+	ctx.Reply(fmt.Sprintf("已清除群 %d 的上下文，共 %d 条", groupID, count))
+	return nil
+}
+
+func runShowUser(ctx *command.Context) error {
+	userID := ctx.QQ("QQ")
+	messages := storage.GetOrCreateConversation(userID).RecentMessages(showCount(ctx))
+	if len(messages) == 0 {
+		ctx.Reply(fmt.Sprintf("%d 还没有私聊记录", userID))
+		return nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%d 最近 %d 条私聊记录：", userID, len(messages)))
+	for _, msg := range messages {
+		speaker := "TA"
+		if msg.Role == "assistant" {
+			speaker = "小牛"
+		}
+		sb.WriteString(fmt.Sprintf("\n[%s] %s: %s", shortTime(msg.Time), speaker, truncateRunes(msg.Content, showContentMaxRune)))
+	}
+	ctx.Reply(sb.String())
+	return nil
+}
+
+func runShowGroup(ctx *command.Context) error {
+	groupID, err := targetGroup(ctx)
+	if err != nil {
+		return err
+	}
+	messages := storage.RecentGroupContextMessages(groupID, showCount(ctx))
+	if len(messages) == 0 {
+		ctx.Reply(fmt.Sprintf("群 %d 还没有上下文记录", groupID))
+		return nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("群 %d 最近 %d 条上下文：", groupID, len(messages)))
+	for _, msg := range messages {
+		sb.WriteString(fmt.Sprintf("\n[%s] %s: %s", shortTime(msg.Time), storage.GetNickname(groupID, msg.UserID), truncateRunes(msg.Content, showContentMaxRune)))
+	}
+	ctx.Reply(sb.String())
+	return nil
+}
+
+func runExportUser(ctx *command.Context) error {
+	path, count, err := storage.ExportConversation(ctx.QQ("QQ"))
+	if err != nil {
+		return err
+	}
+	sendExportFile(ctx, path, count)
+	return nil
+}
+
+func runExportGroup(ctx *command.Context) error {
+	groupID, err := targetGroup(ctx)
+	if err != nil {
+		return err
+	}
+	path, count, err := storage.ExportGroupContext(groupID)
+	if err != nil {
+		return err
+	}
+	sendExportFile(ctx, path, count)
+	return nil
+}
+
+// sendExportFile 尝试通过私聊文件发送导出结果（需 NapCat 与机器人在同一台机器），并回复文件路径
+func sendExportFile(ctx *command.Context, path string, count int) {
+	common.SendAction("upload_private_file", map[string]interface{}{
+		"user_id": ctx.Event.UserID,
+		"file":    path,
+		"name":    filepath.Base(path),
+	})
+	ctx.Reply(fmt.Sprintf("已导出 %d 条记录：%s", count, path))
+}
+
+// showCount 获取查看条数参数（默认 defaultShowCount，不超过历史上限）
+func showCount(ctx *command.Context) int {
+	if !ctx.Has("条数") || ctx.Int("条数") <= 0 {
+		return defaultShowCount
+	}
+	if n := ctx.Int("条数"); n < storage.MaxHistoryMessages {
+		return n
+	}
+	return storage.MaxHistoryMessages
+}
+
+// shortTime 将 RFC3339 时间格式化为 "01-02 15:04"
+func shortTime(s string) string {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return s
+	}
+	return t.Format("01-02 15:04")
+}
+
+// truncateRunes 截断过长的内容
+func truncateRunes(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max]) + "…"
+}
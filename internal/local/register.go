@@ -0,0 +1,29 @@
+package local
+
+import (
+	"context"
+
+	"QQBot/internal/common"
+	"QQBot/internal/handler"
+)
+
+func init() {
+	handler.Register(repeatHandler{})
+}
+
+// repeatHandler 连续相同消息检测（复读）
+type repeatHandler struct{}
+
+func (repeatHandler) Name() string  { return "repeat" }
+func (repeatHandler) Priority() int { return 10 }
+
+func (repeatHandler) Match(event common.QQEvent) bool {
+	return ShouldHandleRepeatMessage(event)
+}
+
+func (repeatHandler) Handle(_ context.Context, event common.QQEvent) handler.Result {
+	if HandleRepeatMessage(event) {
+		return handler.Stop // 触发了复读，不再处理其他逻辑
+	}
+	return handler.Continue
+}
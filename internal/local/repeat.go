@@ -1,73 +1,200 @@
 package local
 
 import (
+	"encoding/json"
+	"fmt"
 	"log"
+	"math/rand"
+	"strings"
 	"sync"
+	"time"
 
 	"QQBot/internal/common"
 )
 
-// 消息队列结构，用于检测连续相同消息
-type messageQueue struct {
-	messages []string
-	mu       sync.RWMutex
+// breakReplies 打断复读时随机发送的内容
+var breakReplies = []string{"打断施法！", "不许复读！", "🤚 打断一下", "复读机都给我停下"}
+
+// repeatChain 一个群当前正在进行的复读链
+type repeatChain struct {
+	key     string             // 归一化后的消息，用于判断是否相同
+	message string             // 复读时发送的消息（CQ 码）
+	count   int                // 链上的消息条数
+	senders map[int64]struct{} // 参与复读的人
+	done    bool               // 本条链已经处理过（复读、打断或未通过概率判定）
+}
+
+// groupRepeat 一个群的复读状态
+type groupRepeat struct {
+	mu        sync.Mutex
+	chain     repeatChain
+	lastFired map[string]time.Time // 归一化消息 -> 上次复读时间
 }
 
 var (
-	groupQueues sync.Map // map[int64]*messageQueue，存储每个群的消息队列
+	groupRepeats sync.Map // map[int64]*groupRepeat，存储每个群的复读状态
 )
 
 // HandleRepeatMessage 处理连续相同消息检测
-// 返回 true 表示已处理（发送了重复消息），false 表示未触发
+// 返回 true 表示已处理（复读或打断了复读），false 表示未触发
 func HandleRepeatMessage(event common.QQEvent) bool {
-	// 1. 过滤条件：跳过机器人自己的消息、空消息
-	if event.UserID == common.BotQQNumber || event.Content == "" {
+	// 1. 过滤条件：跳过机器人自己的消息、无法复读的消息（含 @、回复、语音等）
+	if event.UserID == common.BotQQNumber {
+		return false
+	}
+	key, message, ok := normalizeRepeat(event.RawContent)
+	if !ok {
+		resetRepeatChain(event.GroupID)
 		return false
 	}
 
-	// 2. 获取或创建该群的消息队列
-	queueInterface, _ := groupQueues.LoadOrStore(event.GroupID, &messageQueue{
-		messages: make([]string, 0, common.RepeatMessageQueueSize),
-	})
-	queue := queueInterface.(*messageQueue)
-
-	// 3. 更新队列并检查
-	queue.mu.Lock()
-	defer queue.mu.Unlock()
+	settings := GetRepeatSettings(event.GroupID)
+	value, _ := groupRepeats.LoadOrStore(event.GroupID, &groupRepeat{lastFired: make(map[string]time.Time)})
+	state := value.(*groupRepeat)
 
-	// 添加新消息到队列
-	queue.messages = append(queue.messages, event.Content)
+	state.mu.Lock()
+	defer state.mu.Unlock()
 
-	// 保持队列大小不超过 RepeatMessageQueueSize
-	if len(queue.messages) > common.RepeatMessageQueueSize {
-		queue.messages = queue.messages[len(queue.messages)-common.RepeatMessageQueueSize:]
+	// 2. 更新复读链：与上一条不同则开始新的链
+	chain := &state.chain
+	if chain.key != key {
+		*chain = repeatChain{key: key, message: message, senders: make(map[int64]struct{})}
 	}
+	chain.count++
+	chain.senders[event.UserID] = struct{}{}
 
-	// 4. 检查是否达到触发条件（队列中所有消息都相同）
-	if len(queue.messages) == common.RepeatMessageQueueSize {
-		allSame := true
-		firstMsg := queue.messages[0]
-		for i := 1; i < len(queue.messages); i++ {
-			if queue.messages[i] != firstMsg {
-				allSame = false
-				break
-			}
-		}
+	// 3. 检查是否达到触发条件
+	reached := chain.count >= settings.Threshold
+	if settings.DistinctSenders {
+		reached = len(chain.senders) >= settings.Threshold
+	}
+	if !reached || chain.done {
+		return false
+	}
+	chain.done = true // 每条链只判定一次，之后的相同消息不再触发
 
-		if allSame {
-			// 清空队列，避免重复触发
-			queue.messages = queue.messages[:0]
-			log.Printf("[重复消息] 群 %d 检测到连续 %d 条相同消息: %s", event.GroupID, common.RepeatMessageQueueSize, firstMsg)
-			// 发送相同消息
-			common.SendReply(event, firstMsg)
-			return true
-		}
+	now := time.Now()
+	if last, ok := state.lastFired[key]; ok && now.Sub(last) < settings.Cooldown() {
+		log.Printf("[重复消息] 群 %d 的 %q 冷却中，跳过", event.GroupID, key)
+		return false
+	}
+	roll := rand.Float64()
+	if roll >= settings.Probability+settings.BreakProbability {
+		log.Printf("[重复消息] 群 %d 的 %q 未通过概率判定，跳过", event.GroupID, key)
+		return false
 	}
+	state.lastFired[key] = now
+	pruneRepeatCooldowns(state.lastFired, now, settings.Cooldown())
 
-	return false
+	// 4. 复读或打断
+	if roll < settings.BreakProbability {
+		log.Printf("[重复消息] 群 %d 打断复读（%d 人 %d 条）: %s", event.GroupID, len(chain.senders), chain.count, key)
+		common.SendReply(event, breakReplies[rand.Intn(len(breakReplies))])
+		return true
+	}
+	log.Printf("[重复消息] 群 %d 检测到复读（%d 人 %d 条）: %s", event.GroupID, len(chain.senders), chain.count, key)
+	common.SendReply(event, message)
+	return true
 }
 
 // ShouldHandleRepeatMessage 判断是否应该处理重复消息检测（仅群聊）
 func ShouldHandleRepeatMessage(event common.QQEvent) bool {
 	return event.MsgType == "group" && event.GroupID > 0
 }
+
+// resetRepeatChain 无法复读的消息会打断当前的复读链
+func resetRepeatChain(groupID int64) {
+	if value, ok := groupRepeats.Load(groupID); ok {
+		state := value.(*groupRepeat)
+		state.mu.Lock()
+		state.chain = repeatChain{}
+		state.mu.Unlock()
+	}
+}
+
+// pruneRepeatCooldowns 清理已过冷却期的记录，避免无限增长
+func pruneRepeatCooldowns(lastFired map[string]time.Time, now time.Time, cooldown time.Duration) {
+	for key, last := range lastFired {
+		if now.Sub(last) >= cooldown {
+			delete(lastFired, key)
+		}
+	}
+}
+
+// repeatSegment 消息数组中的一个消息段
+type repeatSegment struct {
+	Type string                 `json:"type"`
+	Data map[string]interface{} `json:"data"`
+}
+
+// normalizeRepeat 将消息数组归一化为用于比较的 key（合并空白，图片按文件标识比较而非每次不同的链接），
+// 同时生成复读时发送的 CQ 码消息；只包含文本、表情、图片的消息才能复读
+func normalizeRepeat(rawContent string) (key string, message string, ok bool) {
+	var segments []repeatSegment
+	if err := json.Unmarshal([]byte(rawContent), &segments); err != nil || len(segments) == 0 {
+		return "", "", false
+	}
+
+	var keyParts, msgParts []string
+	for _, seg := range segments {
+		switch seg.Type {
+		case "text":
+			text := strings.Join(strings.Fields(segString(seg.Data, "text")), " ")
+			if text == "" {
+				continue
+			}
+			keyParts = append(keyParts, text)
+			msgParts = append(msgParts, common.EscapeCQText(text))
+		case "face":
+			id := segString(seg.Data, "id")
+			keyParts = append(keyParts, "[表情:"+id+"]")
+			msgParts = append(msgParts, "[CQ:face,id="+common.EscapeCQParam(id)+"]")
+		case "mface":
+			id := segString(seg.Data, "emoji_id")
+			keyParts = append(keyParts, "[商城表情:"+id+"]")
+			msgParts = append(msgParts, fmt.Sprintf("[CQ:mface,emoji_id=%s,emoji_package_id=%s,key=%s,summary=%s]",
+				common.EscapeCQParam(id), common.EscapeCQParam(segString(seg.Data, "emoji_package_id")),
+				common.EscapeCQParam(segString(seg.Data, "key")), common.EscapeCQParam(segString(seg.Data, "summary"))))
+		case "image":
+			// 同一张图片每次的链接不同，用文件名（图片 MD5）作为标识
+			file := segString(seg.Data, "file")
+			src := segString(seg.Data, "url")
+			if src == "" {
+				src = file
+			}
+			if file == "" {
+				file = src
+			}
+			if src == "" {
+				return "", "", false
+			}
+			keyParts = append(keyParts, "[图片:"+strings.ToLower(file)+"]")
+			image := "[CQ:image,file=" + common.EscapeCQParam(src)
+			if subType := segString(seg.Data, "sub_type"); subType != "" && subType != "0" {
+				image += ",sub_type=" + common.EscapeCQParam(subType) // 保持表情包的显示方式
+			}
+			msgParts = append(msgParts, image+"]")
+		default:
+			// @、回复、语音、文件等不复读
+			return "", "", false
+		}
+	}
+	if len(keyParts) == 0 {
+		return "", "", false
+	}
+	return strings.Join(keyParts, ""), strings.Join(msgParts, ""), true
+}
+
+// segString 读取消息段中的字段（可能是字符串或数字）
+func segString(data map[string]interface{}, name string) string {
+	switch v := data[name].(type) {
+	case string:
+		return v
+	case float64:
+		return fmt.Sprintf("%.0f", v)
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,173 @@
+package local
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"QQBot/internal/command"
+	"QQBot/internal/common"
+	"QQBot/internal/storage"
+)
+
+const repeatSettingsDataName = "repeat_settings" // 按群复读策略的存储名称
+
+// RepeatSettings 一个群的复读策略
+type RepeatSettings struct {
+	Threshold        int     `json:"threshold"`         // 连续多少条相同消息触发
+	DistinctSenders  bool    `json:"distinct_senders"`  // 是否要求来自不同的人
+	CooldownSec      int     `json:"cooldown_sec"`      // 同一句话复读后的冷却秒数
+	Probability      float64 `json:"probability"`       // 复读的概率
+	BreakProbability float64 `json:"break_probability"` // 打断复读的概率
+}
+
+// Cooldown 冷却时间
+func (s RepeatSettings) Cooldown() time.Duration {
+	return time.Duration(s.CooldownSec) * time.Second
+}
+
+var (
+	repeatSettingsMu sync.Mutex
+	repeatSettings   map[int64]*RepeatSettings // 群号 -> 修改过的策略
+)
+
+func init() {
+	command.Register(&command.Command{
+		Name:        "repeat",
+		Aliases:     []string{"复读"},
+		Description: "查看或修改本群的复读策略",
+		Permission:  command.PermAdmin,
+		GroupOnly:   true,
+		Subcommands: []*command.Command{
+			{Name: "show", Aliases: []string{"查看"}, Description: "查看本群的复读策略", Run: runRepeatShow},
+			{
+				Name: "set", Aliases: []string{"设置"},
+				Description: "修改 条数/不同人（on|off）/冷却（秒）/概率/打断概率，概率可写 0.3 或 30%",
+				Args:        []command.Arg{{Name: "字段"}, {Name: "值"}},
+				Run:         runRepeatSet,
+			},
+			{Name: "reset", Aliases: []string{"重置"}, Description: "恢复默认策略", Run: runRepeatReset},
+		},
+	})
+}
+
+// defaultRepeatSettings 环境变量配置的默认策略
+func defaultRepeatSettings() RepeatSettings {
+	return RepeatSettings{
+		Threshold:        common.RepeatThreshold,
+		DistinctSenders:  common.RepeatDistinctSenders,
+		CooldownSec:      int(common.RepeatCooldown / time.Second),
+		Probability:      common.RepeatProbability,
+		BreakProbability: common.RepeatBreakProbability,
+	}
+}
+
+// GetRepeatSettings 获取群的复读策略，未修改过时使用默认策略
+func GetRepeatSettings(groupID int64) RepeatSettings {
+	repeatSettingsMu.Lock()
+	defer repeatSettingsMu.Unlock()
+	loadRepeatSettingsLocked()
+
+	if s, ok := repeatSettings[groupID]; ok {
+		return *s
+	}
+	return defaultRepeatSettings()
+}
+
+// updateRepeatSettings 修改群的复读策略并保存，reset 为 true 时恢复默认
+func updateRepeatSettings(groupID int64, update func(s *RepeatSettings), reset bool) {
+	repeatSettingsMu.Lock()
+	defer repeatSettingsMu.Unlock()
+	loadRepeatSettingsLocked()
+
+	if reset {
+		delete(repeatSettings, groupID)
+	} else {
+		s, ok := repeatSettings[groupID]
+		if !ok {
+			d := defaultRepeatSettings()
+			s = &d
+			repeatSettings[groupID] = s
+		}
+		update(s)
+	}
+	if err := storage.SaveData(repeatSettingsDataName, repeatSettings); err != nil {
+		log.Printf("[重复消息] 保存复读策略失败: %v", err)
+	}
+}
+
+// loadRepeatSettingsLocked 首次使用时从存储加载
+func loadRepeatSettingsLocked() {
+	if repeatSettings != nil {
+		return
+	}
+	repeatSettings = make(map[int64]*RepeatSettings)
+	storage.LoadData(repeatSettingsDataName, &repeatSettings)
+}
+
+func runRepeatShow(ctx *command.Context) error {
+	s := GetRepeatSettings(ctx.Event.GroupID)
+	distinct := "同一人刷屏也算"
+	if s.DistinctSenders {
+		distinct = "需要不同的人"
+	}
+	ctx.Reply(fmt.Sprintf("本群复读策略：\n连续 %d 条相同消息（%s）\n同一句冷却 %d 秒\n复读概率 %.0f%%，打断概率 %.0f%%",
+		s.Threshold, distinct, s.CooldownSec, s.Probability*100, s.BreakProbability*100))
+	return nil
+}
+
+func runRepeatSet(ctx *command.Context) error {
+	value := ctx.String("值")
+
+	var update func(s *RepeatSettings)
+	switch ctx.String("字段") {
+	case "条数", "threshold":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 2 {
+			return command.Usagef("条数应为不小于 2 的整数")
+		}
+		update = func(s *RepeatSettings) { s.Threshold = n }
+	case "不同人", "distinct":
+		var on bool
+		switch strings.ToLower(value) {
+		case "on", "开", "是", "true":
+			on = true
+		case "off", "关", "否", "false":
+		default:
+			return command.Usagef("不同人应为 on 或 off")
+		}
+		update = func(s *RepeatSettings) { s.DistinctSenders = on }
+	case "冷却", "cooldown":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return command.Usagef("冷却应为不小于 0 的秒数")
+		}
+		update = func(s *RepeatSettings) { s.CooldownSec = n }
+	case "概率", "probability":
+		p, err := command.ParseProbability(value)
+		if err != nil {
+			return err
+		}
+		update = func(s *RepeatSettings) { s.Probability = p }
+	case "打断概率", "break":
+		p, err := command.ParseProbability(value)
+		if err != nil {
+			return err
+		}
+		update = func(s *RepeatSettings) { s.BreakProbability = p }
+	default:
+		return command.Usagef("字段应为 条数、不同人、冷却、概率 或 打断概率")
+	}
+
+	updateRepeatSettings(ctx.Event.GroupID, update, false)
+	return runRepeatShow(ctx)
+}
+
+func runRepeatReset(ctx *command.Context) error {
+	updateRepeatSettings(ctx.Event.GroupID, nil, true)
+	ctx.Reply("已恢复默认复读策略")
+	return nil
+}
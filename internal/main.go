@@ -1,50 +1,48 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
 	"strconv"
 	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
 
 	"github.com/gorilla/websocket"
 
+	"QQBot/internal/acl"
+	"QQBot/internal/archive"
+	_ "QQBot/internal/autoreply" // 注册关键词自动回复
+	_ "QQBot/internal/command"   // 注册指令处理器
 	"QQBot/internal/common"
-	"QQBot/internal/deepseek"
-	"QQBot/internal/local"
+	"QQBot/internal/deepseek" // 注册 AI 处理器
+	"QQBot/internal/handler"
+	_ "QQBot/internal/local" // 注册复读处理器、本地指令
+	"QQBot/internal/monitor"
+	"QQBot/internal/quota"
+	"QQBot/internal/reminder"
+	"QQBot/internal/request"
+	"QQBot/internal/schedule"
 	"QQBot/internal/storage"
+	"QQBot/internal/worker"
 )
 
 var (
-	upgrader = websocket.Upgrader{CheckOrigin: func(r *http.Request) bool { return true }}
+	upgrader     = websocket.Upgrader{CheckOrigin: func(r *http.Request) bool { return true }}
+	shuttingDown atomic.Bool // 收到退出信号后不再处理新事件
 )
 
 // --- 逻辑分发器 ---
 
+// dispatch 将消息交给已注册的处理器（各模块在 init() 中注册，按优先级执行）
 func dispatch(event common.QQEvent) {
-	// 0. 检查连续相同消息（仅群聊）
-	if local.ShouldHandleRepeatMessage(event) {
-		if local.HandleRepeatMessage(event) {
-			return // 如果触发了重复消息回复，不再处理其他逻辑
-		}
-	}
-
-	// 1. 本地指令
-	if local.ShouldHandleLocalCommand(event.Content) {
-		local.HandleLocalCommand(event)
-		return
-	}
-
-	// 2. 群聊中@主人（优先级高于普通AI对话）
-	if deepseek.ShouldHandleAtMasterChat(event) {
-		go deepseek.HandleAtMasterChat(event)
-		return
-	}
-
-	// 3. AI 对话
-	if deepseek.ShouldHandleAIChat(event) {
-		go deepseek.HandleAIChat(event)
-	}
+	handler.Dispatch(event)
 }
 
 // --- 通信处理 ---
@@ -52,6 +50,9 @@ func dispatch(event common.QQEvent) {
 func parseEvent(raw map[string]interface{}) common.QQEvent {
 	ev := common.QQEvent{}
 	ev.MsgType, _ = raw["message_type"].(string)
+	if mid, ok := raw["message_id"].(float64); ok {
+		ev.MessageID = int64(mid)
+	}
 
 	// 处理 JSON 中的数字类型
 	if uid, ok := raw["user_id"].(float64); ok {
@@ -62,8 +63,8 @@ func parseEvent(raw map[string]interface{}) common.QQEvent {
 	}
 
 	// 先更新发送者的昵称映射（群聊时），这样如果消息中 @ 的是发送者自己，就能用最新昵称
+	nickname := extractNickname(raw)
 	if ev.MsgType == "group" && ev.GroupID > 0 && ev.UserID > 0 {
-		nickname := extractNickname(raw)
 		if nickname != "" {
 			storage.UpdateNicknameMap(ev.GroupID, ev.UserID, nickname)
 		} else {
@@ -71,27 +72,89 @@ func parseEvent(raw map[string]interface{}) common.QQEvent {
 		}
 	}
 
+	if sender, ok := raw["sender"].(map[string]interface{}); ok {
+		ev.SenderRole, _ = sender["role"].(string)
+	}
+
 	// 解析消息内容（array 格式）
-	ev.RawContent, ev.Content, ev.AtType = parseMessageArray(raw, ev.GroupID)
+	ev.RawContent, ev.Content, ev.AtType, ev.AtQQs = parseMessageArray(raw, ev.GroupID)
 
-	// 添加到群聊上下文（所有群聊消息都添加）
-	if ev.MsgType == "group" && ev.GroupID > 0 && ev.Content != "" && ev.UserID != common.BotQQNumber {
+	// 添加到群聊上下文（被名单拒绝的消息按配置决定是否添加）
+	if ev.MsgType == "group" && ev.GroupID > 0 && ev.Content != "" && ev.UserID != common.BotQQNumber && acl.ShouldRecord(ev) {
 		storage.AddGroupContextMessage(ev.GroupID, ev.UserID, ev.Content)
 	}
 
+	// 完整归档群消息（不受上下文长度和单条长度限制）
+	if ev.MsgType == "group" && ev.GroupID > 0 && acl.ShouldRecord(ev) {
+		archiveMessage(raw, ev, nickname)
+	}
+
 	return ev
 }
 
+// parseSentEvent 处理机器人自己发出的消息（post_type 为 message_sent），只归档不分发
+func parseSentEvent(raw map[string]interface{}) {
+	if msgType, _ := raw["message_type"].(string); msgType != "group" {
+		return
+	}
+	ev := common.QQEvent{MsgType: "group", UserID: common.BotQQNumber}
+	if mid, ok := raw["message_id"].(float64); ok {
+		ev.MessageID = int64(mid)
+	}
+	if gid, ok := raw["group_id"].(float64); ok {
+		ev.GroupID = int64(gid)
+	}
+	if ev.GroupID == 0 {
+		return
+	}
+	_, ev.Content, _, _ = parseMessageArray(raw, ev.GroupID)
+	archiveMessage(raw, ev, extractNickname(raw))
+}
+
+// archiveMessage 将群消息连同原始消息段写入归档
+func archiveMessage(raw map[string]interface{}, ev common.QQEvent, nickname string) {
+	msg := archive.Message{
+		MessageID: ev.MessageID,
+		GroupID:   ev.GroupID,
+		UserID:    ev.UserID,
+		Nickname:  nickname,
+		Content:   ev.Content,
+		Segments:  parseSegments(raw),
+	}
+	if t, ok := raw["time"].(float64); ok && t > 0 {
+		msg.Time = time.Unix(int64(t), 0)
+	}
+	archive.Append(msg)
+}
+
+// parseSegments 提取消息数组中的全部消息段（含 image、face 等解析内容时跳过的类型）
+func parseSegments(raw map[string]interface{}) []archive.Segment {
+	msgArray, ok := raw["message"].([]interface{})
+	if !ok {
+		return nil
+	}
+	segments := make([]archive.Segment, 0, len(msgArray))
+	for _, item := range msgArray {
+		if msgObj, ok := item.(map[string]interface{}); ok {
+			seg := archive.Segment{}
+			seg.Type, _ = msgObj["type"].(string)
+			seg.Data, _ = msgObj["data"].(map[string]interface{})
+			segments = append(segments, seg)
+		}
+	}
+	return segments
+}
+
 // parseMessageArray 解析消息数组（array 格式）
-// 返回：原始 JSON、解析后的内容、@类型
-func parseMessageArray(raw map[string]interface{}, groupID int64) (rawJSON string, content string, atType int) {
+// 返回：原始 JSON、解析后的内容、@类型、按顺序 @ 的 QQ 号
+func parseMessageArray(raw map[string]interface{}, groupID int64) (rawJSON string, content string, atType int, atQQs []int64) {
 	msgArray, ok := raw["message"].([]interface{})
 	if !ok {
 		// 保存原始 JSON 用于调试
 		if jsonBytes, err := json.Marshal(raw["message"]); err == nil {
 			rawJSON = string(jsonBytes)
 		}
-		return rawJSON, "", common.AtNone
+		return rawJSON, "", common.AtNone, nil
 	}
 
 	// 保存原始 JSON 用于调试
@@ -121,6 +184,8 @@ func parseMessageArray(raw map[string]interface{}, groupID int64) (rawJSON strin
 					}
 
 					if atQQ > 0 {
+						atQQs = append(atQQs, atQQ)
+
 						// 判断 @ 的类型（优先级：主人 > 机器人 > 其他人）
 						if common.MasterQQNumber > 0 && atQQ == common.MasterQQNumber {
 							if atType == common.AtNone || atType == common.AtOthers {
@@ -144,6 +209,7 @@ func parseMessageArray(raw map[string]interface{}, groupID int64) (rawJSON strin
 				// 处理文本消息
 				if data, ok := msgObj["data"].(map[string]interface{}); ok {
 					if text, ok := data["text"].(string); ok {
+						// 保持原文，交给 AI 前拼接群聊记录时再转义（见 storage.FormatGroupMessage）
 						contentParts = append(contentParts, text)
 					}
 				}
@@ -153,7 +219,73 @@ func parseMessageArray(raw map[string]interface{}, groupID int64) (rawJSON strin
 	}
 
 	content = strings.TrimSpace(strings.Join(contentParts, ""))
-	return rawJSON, content, atType
+	return rawJSON, content, atType, atQQs
+}
+
+// parseRequestEvent 解析好友/群请求事件
+func parseRequestEvent(raw map[string]interface{}) common.RequestEvent {
+	req := common.RequestEvent{}
+	req.RequestType, _ = raw["request_type"].(string)
+	req.SubType, _ = raw["sub_type"].(string)
+	req.Comment, _ = raw["comment"].(string)
+	req.Flag, _ = raw["flag"].(string)
+
+	if uid, ok := raw["user_id"].(float64); ok {
+		req.UserID = int64(uid)
+	}
+	if gid, ok := raw["group_id"].(float64); ok {
+		req.GroupID = int64(gid)
+	}
+	return req
+}
+
+// parseMetaEvent 解析心跳、生命周期等元事件
+func parseMetaEvent(raw map[string]interface{}) common.MetaEvent {
+	ev := common.MetaEvent{}
+	ev.MetaEventType, _ = raw["meta_event_type"].(string)
+	ev.SubType, _ = raw["sub_type"].(string)
+
+	if interval, ok := raw["interval"].(float64); ok {
+		ev.Interval = int64(interval)
+	}
+	// 未上报状态时视为正常
+	ev.Online, ev.Good = true, true
+	if status, ok := raw["status"].(map[string]interface{}); ok {
+		if online, ok := status["online"].(bool); ok {
+			ev.Online = online
+		}
+		if good, ok := status["good"].(bool); ok {
+			ev.Good = good
+		}
+	}
+	return ev
+}
+
+// parseNoticeEvent 解析通知事件（群消息撤回等）
+func parseNoticeEvent(raw map[string]interface{}) common.NoticeEvent {
+	ev := common.NoticeEvent{}
+	ev.NoticeType, _ = raw["notice_type"].(string)
+
+	if gid, ok := raw["group_id"].(float64); ok {
+		ev.GroupID = int64(gid)
+	}
+	if uid, ok := raw["user_id"].(float64); ok {
+		ev.UserID = int64(uid)
+	}
+	if oid, ok := raw["operator_id"].(float64); ok {
+		ev.OperatorID = int64(oid)
+	}
+	if mid, ok := raw["message_id"].(float64); ok {
+		ev.MessageID = int64(mid)
+	}
+	return ev
+}
+
+// handleNotice 处理通知事件：群消息撤回时在归档中标记
+func handleNotice(ev common.NoticeEvent) {
+	if ev.NoticeType == "group_recall" {
+		archive.MarkRecalled(ev.GroupID, ev.MessageID, ev.OperatorID)
+	}
 }
 
 // extractNickname 从消息中提取昵称
@@ -183,8 +315,10 @@ func wsHandler(w http.ResponseWriter, r *http.Request) {
 
 	log.Println("✨ NapCat 成功连接")
 
+	stopWatch := monitor.Watch(conn)
 	defer func() {
-		common.ClearWebSocketConn()
+		stopWatch()
+		common.ClearWebSocketConn(conn)
 		conn.Close()
 	}()
 
@@ -194,13 +328,25 @@ func wsHandler(w http.ResponseWriter, r *http.Request) {
 			log.Printf("连接中断: %v", err)
 			break
 		}
+		if shuttingDown.Load() {
+			continue
+		}
 		var raw map[string]interface{}
 		if err := json.Unmarshal(msg, &raw); err == nil {
-			if pt, _ := raw["post_type"].(string); pt == "message" {
+			switch pt, _ := raw["post_type"].(string); pt {
+			case "message":
 				// 打印原始消息用于调试
 				//rawJSON, _ := json.MarshalIndent(raw, "", "  ")
 				//log.Printf("[DEBUG] 收到原始消息:\n%s\n", rawJSON)
 				dispatch(parseEvent(raw))
+			case "message_sent":
+				parseSentEvent(raw)
+			case "notice":
+				handleNotice(parseNoticeEvent(raw))
+			case "request":
+				request.HandleRequestEvent(parseRequestEvent(raw))
+			case "meta_event":
+				monitor.HandleMetaEvent(parseMetaEvent(raw))
 			}
 		}
 	}
@@ -210,9 +356,54 @@ func main() {
 	if common.DeepSeekAPIKey == "" {
 		log.Fatal("错误：未找到环境变量 DEEPSEEK_API_KEY，请先设置！")
 	}
-	http.HandleFunc("/ws", wsHandler)
-	log.Printf("🤖 小牛系统已就绪，端口%s", common.ListenPort)
-	if err := http.ListenAndServe(common.ListenPort, nil); err != nil {
-		log.Fatal("服务器启动失败: ", err)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws", wsHandler)
+	server := &http.Server{Addr: common.ListenPort, Handler: mux}
+
+	go func() {
+		log.Printf("🤖 小牛系统已就绪，端口%s", common.ListenPort)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal("服务器启动失败: ", err)
+		}
+	}()
+
+	deepseek.StartMentionDigests()
+	schedule.Start()
+	reminder.Start()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
+	shutdown(server)
+}
+
+// shutdown 优雅退出：停止接收事件和定时检查 → 等待 AI 任务 → 保存数据 → 关闭连接和服务器
+func shutdown(server *http.Server) {
+	log.Println("👋 收到退出信号，正在关闭...")
+	shuttingDown.Store(true)
+
+	// 先停掉后台定时检查（等当前这一轮结束），避免关闭存储后仍有读写，或排空队列后又提交新的 AI 任务
+	schedule.Stop()
+	reminder.Stop()
+	deepseek.StopMentionDigests()
+
+	drainCtx, cancel := context.WithTimeout(context.Background(), common.ShutdownTimeout)
+	defer cancel()
+	if n := worker.Drain(drainCtx); n > 0 {
+		log.Printf("[退出] 等待超时，已取消 %d 个未完成的 AI 任务", n)
+	}
+
+	storage.FlushAll()
+	quota.Flush()
+	storage.Close()
+
+	common.ShutdownWebSocketConn()
+
+	serverCtx, cancelServer := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelServer()
+	if err := server.Shutdown(serverCtx); err != nil {
+		log.Printf("[退出] 关闭服务器失败: %v", err)
 	}
+	log.Println("✅ 已安全退出")
 }
@@ -0,0 +1,74 @@
+// migrate 将 data/ 目录下的 JSON 文件一次性迁移到嵌入式数据库
+//
+// 用法（需先停止机器人）：
+//
+//	go run ./internal/migrate -data data -db data/qqbot.db
+//
+// 迁移完成后设置 STORAGE_BACKEND=bolt 启动机器人，原 JSON 文件保持不变，可作为备份保留
+package main
+
+import (
+	"errors"
+	"flag"
+	"log"
+
+	"QQBot/internal/common"
+	"QQBot/internal/storage"
+)
+
+func main() {
+	dataDir := flag.String("data", storage.HistoryDataDir, "JSON 数据目录")
+	dbPath := flag.String("db", common.StorageDBPath, "目标数据库文件（默认取 STORAGE_DB_PATH）")
+	overwrite := flag.Bool("overwrite", false, "数据库中已存在的数据是否覆盖")
+	flag.Parse()
+
+	src, err := storage.OpenStore("json", *dataDir, "")
+	if err != nil {
+		log.Fatalf("打开 JSON 目录失败: %v", err)
+	}
+	dst, err := storage.OpenStore("bolt", "", *dbPath)
+	if err != nil {
+		log.Fatalf("打开数据库失败: %v", err)
+	}
+	defer dst.Close()
+
+	total, skipped, failed := 0, 0, 0
+	for _, kind := range storage.AllKinds {
+		keys, err := src.Keys(kind)
+		if err != nil {
+			log.Fatalf("列出 %s 失败: %v", kind, err)
+		}
+
+		for _, id := range keys {
+			if !*overwrite {
+				if _, err := dst.Get(kind, id); err == nil {
+					skipped++
+					continue
+				} else if !errors.Is(err, storage.ErrNotFound) {
+					log.Printf("读取数据库 %s/%s 失败: %v", kind, id, err)
+					failed++
+					continue
+				}
+			}
+
+			data, err := src.Get(kind, id)
+			if err != nil {
+				log.Printf("读取 %s/%s 失败: %v", kind, id, err)
+				failed++
+				continue
+			}
+			if err := dst.Put(kind, id, data); err != nil {
+				log.Printf("写入 %s/%s 失败: %v", kind, id, err)
+				failed++
+				continue
+			}
+			total++
+		}
+		log.Printf("%s: %d 条", kind, len(keys))
+	}
+
+	log.Printf("迁移完成：写入 %d 条，跳过已存在 %d 条，失败 %d 条", total, skipped, failed)
+	if failed > 0 {
+		log.Fatal("部分数据迁移失败，请检查日志")
+	}
+}
@@ -0,0 +1,117 @@
+package monitor
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"QQBot/internal/common"
+)
+
+const watchdogTick = time.Second // 心跳超时检查周期
+
+var (
+	mu            sync.Mutex
+	lastHeartbeat time.Time     // 最近一次收到心跳（或建立连接）的时间
+	interval      time.Duration // NapCat 上报的心跳间隔，0 表示尚未收到心跳
+	outageStart   time.Time     // 断线开始时间，非零表示当前处于断线状态
+	lastOnline    = true        // 上一次心跳中的 QQ 在线状态
+)
+
+// Watch 开始监控新建立的连接，返回的 stop 函数需在连接结束时调用
+// 连续错过 HeartbeatMissedLimit 次心跳时主动关闭连接，等待 NapCat 重连
+func Watch(conn *websocket.Conn) (stop func()) {
+	mu.Lock()
+	outage := outageStart
+	outageStart = time.Time{}
+	lastHeartbeat = time.Now()
+	interval = 0
+	mu.Unlock()
+
+	if !outage.IsZero() {
+		notifyOutage(outage, time.Now())
+	}
+
+	done := make(chan struct{})
+	go watchdog(conn, done)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			mu.Lock()
+			defer mu.Unlock()
+			if outageStart.IsZero() {
+				outageStart = time.Now()
+			}
+		})
+	}
+}
+
+// HandleMetaEvent 处理心跳与生命周期元事件
+func HandleMetaEvent(ev common.MetaEvent) {
+	switch ev.MetaEventType {
+	case "heartbeat":
+		mu.Lock()
+		lastHeartbeat = time.Now()
+		interval = time.Duration(ev.Interval) * time.Millisecond
+		wasOnline := lastOnline
+		lastOnline = ev.Online
+		mu.Unlock()
+
+		if wasOnline && !ev.Online {
+			log.Println("[心跳] ⚠️ NapCat 报告 QQ 已离线")
+		} else if !wasOnline && ev.Online {
+			log.Println("[心跳] QQ 已恢复在线")
+		}
+		if !ev.Good {
+			log.Println("[心跳] ⚠️ NapCat 报告运行状态异常")
+		}
+	case "lifecycle":
+		log.Printf("[生命周期] NapCat 事件: %s", ev.SubType)
+	}
+}
+
+// watchdog 定期检查心跳是否超时
+func watchdog(conn *websocket.Conn, done <-chan struct{}) {
+	ticker := time.NewTicker(watchdogTick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+
+		mu.Lock()
+		last, iv := lastHeartbeat, interval
+		dead := iv > 0 && time.Since(last) > iv*common.HeartbeatMissedLimit
+		if dead {
+			// 断线从最后一次心跳算起
+			outageStart = last
+		}
+		mu.Unlock()
+
+		if dead {
+			log.Printf("[心跳] 已连续 %d 次未收到心跳（间隔 %v，最后心跳 %s），关闭连接",
+				common.HeartbeatMissedLimit, iv, last.Format("15:04:05"))
+			common.CloseWebSocketConn(conn)
+			return
+		}
+	}
+}
+
+// notifyOutage 重连后，如果断线时间超过阈值则私聊通知主人
+func notifyOutage(start, end time.Time) {
+	outage := end.Sub(start).Round(time.Second)
+	log.Printf("[心跳] 连接已恢复，断线时长 %v", outage)
+	if outage < common.OutageNotifyThreshold || common.MasterQQNumber == 0 {
+		return
+	}
+	common.SendPrivateMessage(common.MasterQQNumber, fmt.Sprintf("爸爸，小牛刚才和 NapCat 失联了 %v（%s ~ %s），现在已经恢复连接啦",
+		outage, start.Format("01-02 15:04:05"), end.Format("01-02 15:04:05")))
+}
@@ -0,0 +1,67 @@
+package quota
+
+import (
+	"sync"
+	"time"
+)
+
+// tokenBucket 令牌桶：容量 burst，每 refill 恢复一个令牌
+type tokenBucket struct {
+	tokens     float64
+	updated    time.Time
+	lastNotice time.Time // 上次发送“慢一点”提示的时间
+}
+
+// bucketSet 按 key（用户/群）管理令牌桶
+type bucketSet struct {
+	mu      sync.Mutex
+	buckets map[int64]*tokenBucket
+}
+
+func newBucketSet() *bucketSet {
+	return &bucketSet{buckets: make(map[int64]*tokenBucket)}
+}
+
+// take 尝试消耗一个令牌；失败时 notify 表示本轮冷却内还未提示过
+func (s *bucketSet) take(key int64, burst int, refill time.Duration, now time.Time) (ok bool, notify bool) {
+	if burst <= 0 || refill <= 0 {
+		return true, false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, exists := s.buckets[key]
+	if !exists {
+		b = &tokenBucket{tokens: float64(burst), updated: now}
+		s.buckets[key] = b
+	}
+
+	// 按经过的时间补充令牌
+	b.tokens += float64(now.Sub(b.updated)) / float64(refill)
+	if b.tokens > float64(burst) {
+		b.tokens = float64(burst)
+	}
+	b.updated = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, false
+	}
+
+	// 每个冷却周期最多提示一次
+	if now.Sub(b.lastNotice) >= refill {
+		b.lastNotice = now
+		return false, true
+	}
+	return false, false
+}
+
+// refund 退还一个令牌（另一层限制拒绝时使用，避免白白消耗）
+func (s *bucketSet) refund(key int64, burst int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if b, ok := s.buckets[key]; ok && b.tokens+1 <= float64(burst) {
+		b.tokens++
+	}
+}
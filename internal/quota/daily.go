@@ -0,0 +1,79 @@
+package quota
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"QQBot/internal/storage"
+)
+
+const dailyDataName = "quota" // 每日配额计数的存储名称
+
+// dailyUsage 用户当日的用量
+type dailyUsage struct {
+	Requests int  `json:"requests"`
+	Tokens   int  `json:"tokens"`
+	Notified bool `json:"notified"` // 今日是否已提示过额度用完
+}
+
+// dailyCounters 每日配额计数（持久化，跨天自动清零）
+type dailyCounters struct {
+	Date  string                `json:"date"`
+	Users map[int64]*dailyUsage `json:"users"`
+	mu    sync.Mutex
+}
+
+var (
+	daily     *dailyCounters
+	dailyOnce sync.Once
+)
+
+// getDaily 获取每日计数（首次使用时加载）
+func getDaily() *dailyCounters {
+	dailyOnce.Do(func() {
+		daily = loadDailyFromFile()
+		if daily == nil {
+			daily = &dailyCounters{Users: make(map[int64]*dailyUsage)}
+		}
+	})
+	return daily
+}
+
+// userLocked 获取用户当日用量，日期变化时清零
+func (d *dailyCounters) userLocked(userID int64, now time.Time) *dailyUsage {
+	today := now.Format("2006-01-02")
+	if d.Date != today {
+		d.Date = today
+		d.Users = make(map[int64]*dailyUsage)
+	}
+	u, ok := d.Users[userID]
+	if !ok {
+		u = &dailyUsage{}
+		d.Users[userID] = u
+	}
+	return u
+}
+
+// saveToFile 保存每日计数
+func (d *dailyCounters) saveToFile() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := storage.SaveData(dailyDataName, d); err != nil {
+		log.Printf("[配额] 保存失败: %v", err)
+	}
+}
+
+// loadDailyFromFile 加载每日计数
+func loadDailyFromFile() *dailyCounters {
+	var d dailyCounters
+	if !storage.LoadData(dailyDataName, &d) {
+		// 不存在是正常的
+		return nil
+	}
+	if d.Users == nil {
+		d.Users = make(map[int64]*dailyUsage)
+	}
+	return &d
+}
@@ -0,0 +1,105 @@
+package quota
+
+import (
+	"log"
+	"time"
+
+	"QQBot/internal/common"
+)
+
+// 超限提示（保持人设）
+const (
+	userSlowDownNotice  = "慢一点嘛，小牛说话也要喘口气的～等一下下再来找我好不好？"
+	groupSlowDownNotice = "大家说得太快啦，小牛有点应付不过来了，让我歇一小会儿～"
+	dailyLimitNotice    = "今天小牛已经陪你聊了好多好多啦，有点累了，明天再来找我玩吧～"
+)
+
+var (
+	userBuckets  = newBucketSet()
+	groupBuckets = newBucketSet()
+)
+
+// Check 判断消息能否触发 AI 调用，通过时计入当日请求数
+// 不通过时 notice 为需要回复的提示，每个冷却周期（或每天）最多提示一次，其余时候为空
+func Check(event common.QQEvent) (ok bool, notice string) {
+	if isExempt(event.UserID) {
+		return true, ""
+	}
+	now := time.Now()
+
+	d := getDaily()
+	d.mu.Lock()
+	u := d.userLocked(event.UserID, now)
+	overDaily := (common.AIDailyRequests > 0 && u.Requests >= common.AIDailyRequests) ||
+		(common.AIDailyTokens > 0 && u.Tokens >= common.AIDailyTokens)
+	if overDaily {
+		notify := !u.Notified
+		u.Notified = true
+		log.Printf("[配额] 用户%d 今日额度已用完（请求 %d，token %d）", event.UserID, u.Requests, u.Tokens)
+		d.mu.Unlock()
+		if notify {
+			go d.saveToFile()
+			return false, dailyLimitNotice
+		}
+		return false, ""
+	}
+	d.mu.Unlock()
+
+	if ok, notify := userBuckets.take(event.UserID, common.AIUserBurst, common.AIUserRefill, now); !ok {
+		log.Printf("[配额] 用户%d 触发过于频繁", event.UserID)
+		return false, noticeIf(notify, userSlowDownNotice)
+	}
+	if event.GroupID > 0 {
+		if ok, notify := groupBuckets.take(event.GroupID, common.AIGroupBurst, common.AIGroupRefill, now); !ok {
+			userBuckets.refund(event.UserID, common.AIUserBurst)
+			log.Printf("[配额] 群%d 触发过于频繁", event.GroupID)
+			return false, noticeIf(notify, groupSlowDownNotice)
+		}
+	}
+
+	d.mu.Lock()
+	d.userLocked(event.UserID, now).Requests++
+	d.mu.Unlock()
+	go d.saveToFile()
+	return true, ""
+}
+
+// RecordTokens 记录用户本次 AI 调用消耗的 token 数
+func RecordTokens(userID int64, tokens int) {
+	if userID == 0 || tokens <= 0 {
+		return
+	}
+	d := getDaily()
+	d.mu.Lock()
+	d.userLocked(userID, time.Now()).Tokens += tokens
+	d.mu.Unlock()
+	go d.saveToFile()
+}
+
+// Flush 同步保存每日计数（程序退出前调用）
+func Flush() {
+	getDaily().saveToFile()
+}
+
+// isExempt 主人、主人女朋友和管理员不受冷却和配额限制
+func isExempt(userID int64) bool {
+	if userID == 0 {
+		return false
+	}
+	if userID == common.MasterQQNumber || userID == common.MasterGirlFriendQQNumber {
+		return true
+	}
+	for _, qq := range common.AdminQQNumbers {
+		if qq == userID {
+			return true
+		}
+	}
+	return false
+}
+
+func noticeIf(notify bool, notice string) string {
+	if notify {
+		return notice
+	}
+	return ""
+}
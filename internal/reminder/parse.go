@@ -0,0 +1,355 @@
+package reminder
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	errNoTime  = errors.New("没有识别到时间")
+	errPast    = errors.New("时间已经过去了")
+	errBadDate = errors.New("没有这一天哦，检查一下日期吧～")
+	errTooFar  = errors.New("太久以后的事情小牛记不住啦，一年以内的才行哦")
+)
+
+var (
+	// 提醒意图："提醒我"、"记得提醒我一下"、"叫我"（必须同时带有时间，避免 "你能提醒我吗"、"你可以叫我小明" 被误认）
+	reTrigger  = regexp.MustCompile(`(?:记得)?(?:提醒|叫)(?:一下)?我(?:一下)?`)
+	reExplicit = regexp.MustCompile(`提醒(?:一下)?我`)
+	// 规则解析不了但明显说了时间的说法，交给 AI 解析，如 "过一会儿提醒我"、"月底提醒我"
+	reVagueTime = regexp.MustCompile(`一会儿?|一下子|待会|等会|过会|回头|晚一?点|早一?点|月初|月中|月底|月末|周末|年底|下个?月|过几天|过两天|改天|明年`)
+
+	// 中文数字（后面跟时间单位时才转换，避免改动提醒内容中的其他数字）
+	reCNNumber = regexp.MustCompile(`([零〇一二两三四五六七八九十]+)(个半?(?:小时|钟头|星期|礼拜|月)|分钟|分|秒|点|时|天|日|号|月|年|刻)`)
+	// "一点"、"两点" 也常表示 "一些"（"喝一点水"、"多吃一点"），前面是日期/时段、句首或标点，
+	// 或后面跟着 "钟/半/整/几分/刻" 时才当作钟点
+	reClockBefore = regexp.MustCompile(`(?:^|[\s\p{P}]|今天|明天|后天|今晚|明晚|今早|明早|今儿|明儿|凌晨|早上|早晨|清晨|上午|中午|下午|傍晚|晚上|夜里|夜晚|到|在|[日号]|(?:周|星期|礼拜)[一二三四五六日天1-7])\s*$`)
+	reClockAfter  = regexp.MustCompile(`^\s*(?:钟|半|整|[零一二三四五六七八九十\d]+\s*分|[一二三123]\s*刻)`)
+
+	// 相对时间："10分钟后"、"2个小时以后"、"1个半小时后"、"半小时后"、"3天后"
+	reRelative = regexp.MustCompile(`(\d+)\s*个?(半)?\s*(分钟|分|小时|钟头|天|周|星期|礼拜)\s*(?:之后|以后|后)`)
+	reHalfHour = regexp.MustCompile(`半\s*个?\s*(?:小时|钟头)\s*(?:之后|以后|后)`)
+
+	// 日期
+	reFullDate = regexp.MustCompile(`(\d{4})\s*[年/-]\s*(\d{1,2})\s*[月/-]\s*(\d{1,2})\s*[日号]?`)
+	reMonthDay = regexp.MustCompile(`(\d{1,2})\s*月\s*(\d{1,2})\s*[日号]`)
+	reDayWord  = regexp.MustCompile(`大后天|后天|明天|明早|明晚|明儿|今天|今早|今晚|今夜|今儿`)
+	reWeekday  = regexp.MustCompile(`(下下|下个?|这个?|本)?(?:周|星期|礼拜)([一二三四五六日天1-7])`)
+	reDayOnly  = regexp.MustCompile(`(\d{1,2})\s*[日号]`)
+
+	// 时刻："下午3点半"、"15:30"、"9点15分"、"晚上8点"
+	reClock  = regexp.MustCompile(`(凌晨|早上|早晨|清晨|上午|中午|下午|傍晚|晚上|夜里|夜晚|晚|早)?\s*(\d{1,2})(?:\s*[:：]\s*(\d{2})|\s*(?:点钟?|时))(?:\s*(\d)\s*刻|\s*(\d{1,2})\s*分?|\s*(半))?`)
+	rePeriod = regexp.MustCompile(`凌晨|早上|早晨|清晨|上午|中午|下午|傍晚|晚上|夜里|夜晚`)
+
+	reMention = regexp.MustCompile(`@【[^】]*】\S*`)
+)
+
+// 只说了时段没说几点时的默认时刻
+var periodDefaultHour = map[string]int{
+	"凌晨": 1, "早上": 8, "早晨": 8, "清晨": 7, "早": 8, "上午": 9,
+	"中午": 12, "下午": 15, "傍晚": 18, "晚上": 20, "夜里": 22, "夜晚": 22, "晚": 20,
+}
+
+// isReminderRequest 判断消息是否在请求设置提醒：规则能识别出时间，
+// 或明确说了 "提醒我" 且带有规则识别不了的时间说法（交给 AI 解析）；其余交给普通对话
+func isReminderRequest(content string, now time.Time) bool {
+	if !reTrigger.MatchString(content) {
+		return false
+	}
+	if _, _, err := parseReminder(content, now); !errors.Is(err, errNoTime) {
+		return true
+	}
+	return reExplicit.MatchString(content) && reVagueTime.MatchString(cleanContent(content))
+}
+
+// parseReminder 解析 "明天下午三点提醒我交报告" 这类消息，返回提醒时间和提醒内容
+// 优先在 "提醒我" 之前查找时间，找不到再查找之后的部分，避免把提醒内容里的数字当成时间
+func parseReminder(content string, now time.Time) (time.Time, string, error) {
+	content = cleanContent(content)
+	idx := reTrigger.FindStringIndex(content)
+	if idx == nil {
+		return time.Time{}, "", errNoTime
+	}
+	before, after := content[:idx[0]], content[idx[1]:]
+
+	if due, rest, err := parseTime(before, now); !errors.Is(err, errNoTime) {
+		return due, cleanTask(rest + " " + after), err
+	}
+	due, rest, err := parseTime(after, now)
+	return due, cleanTask(before + " " + rest), err
+}
+
+// parseTime 在文本中查找时间表达式，返回对应的时间和去掉时间后剩下的文本
+func parseTime(s string, now time.Time) (time.Time, string, error) {
+	s = normalizeNumbers(s)
+
+	// 相对时间
+	if m := reRelative.FindStringSubmatchIndex(s); m != nil {
+		var unit time.Duration
+		switch s[m[6]:m[7]] {
+		case "分钟", "分":
+			unit = time.Minute
+		case "小时", "钟头":
+			unit = time.Hour
+		case "天":
+			unit = 24 * time.Hour
+		default: // 周、星期、礼拜
+			unit = 7 * 24 * time.Hour
+		}
+		// 先按上限检查数量，避免 "99999999天后" 这样的数字相乘溢出
+		n, err := strconv.Atoi(s[m[2]:m[3]])
+		if err != nil || time.Duration(n) > maxAhead/unit {
+			return time.Time{}, s[:m[0]] + s[m[1]:], errTooFar
+		}
+		d := time.Duration(n) * unit
+		if m[4] >= 0 && unit == time.Hour {
+			d += 30 * time.Minute
+		}
+		return now.Add(d), s[:m[0]] + s[m[1]:], nil
+	}
+	if m := reHalfHour.FindStringIndex(s); m != nil {
+		return now.Add(30 * time.Minute), s[:m[0]] + s[m[1]:], nil
+	}
+
+	// 日期
+	year, month, day := now.Date()
+	hasDate := false
+	impliedPeriod := ""
+	rest := s
+
+	switch {
+	case reFullDate.MatchString(rest):
+		m := reFullDate.FindStringSubmatch(rest)
+		y, _ := strconv.Atoi(m[1])
+		mo, _ := strconv.Atoi(m[2])
+		d, _ := strconv.Atoi(m[3])
+		year, month, day = y, time.Month(mo), d
+		rest = strings.Replace(rest, m[0], " ", 1)
+		if !validDate(year, month, day) {
+			return time.Time{}, rest, errBadDate
+		}
+		hasDate = true
+	case reMonthDay.MatchString(rest):
+		m := reMonthDay.FindStringSubmatch(rest)
+		mo, _ := strconv.Atoi(m[1])
+		d, _ := strconv.Atoi(m[2])
+		month, day = time.Month(mo), d
+		if time.Date(year, month, day, 23, 59, 0, 0, now.Location()).Before(now) {
+			year++
+		}
+		rest = strings.Replace(rest, m[0], " ", 1)
+		if !validDate(year, month, day) {
+			return time.Time{}, rest, errBadDate
+		}
+		hasDate = true
+	case reDayWord.MatchString(rest):
+		word := reDayWord.FindString(rest)
+		offset := 0
+		switch {
+		case strings.HasPrefix(word, "大后"):
+			offset = 3
+		case strings.HasPrefix(word, "后"):
+			offset = 2
+		case strings.HasPrefix(word, "明"):
+			offset = 1
+		}
+		switch word {
+		case "明早", "今早":
+			impliedPeriod = "早上"
+		case "明晚", "今晚", "今夜":
+			impliedPeriod = "晚上"
+		}
+		year, month, day = now.AddDate(0, 0, offset).Date()
+		rest = strings.Replace(rest, word, " ", 1)
+		hasDate = true
+	case reWeekday.MatchString(rest):
+		m := reWeekday.FindStringSubmatch(rest)
+		year, month, day = weekdayDate(now, m[1], m[2]).Date()
+		rest = strings.Replace(rest, m[0], " ", 1)
+		hasDate = true
+	case reDayOnly.MatchString(rest):
+		m := reDayOnly.FindStringSubmatch(rest)
+		d, _ := strconv.Atoi(m[1])
+		day = d
+		if time.Date(year, month, day, 23, 59, 0, 0, now.Location()).Before(now) {
+			year, month, _ = time.Date(year, month+1, 1, 0, 0, 0, 0, now.Location()).Date()
+		}
+		rest = strings.Replace(rest, m[0], " ", 1)
+		if !validDate(year, month, day) {
+			return time.Time{}, rest, errBadDate
+		}
+		hasDate = true
+	}
+
+	// 时刻
+	hour, minute := 9, 0
+	hasClock := false
+	period := impliedPeriod
+	if m := reClock.FindStringSubmatch(rest); m != nil {
+		hour, _ = strconv.Atoi(m[2])
+		switch {
+		case m[3] != "":
+			minute, _ = strconv.Atoi(m[3])
+		case m[4] != "":
+			q, _ := strconv.Atoi(m[4])
+			minute = q * 15
+		case m[5] != "":
+			minute, _ = strconv.Atoi(m[5])
+		case m[6] != "":
+			minute = 30
+		}
+		if m[1] != "" {
+			period = m[1]
+		}
+		if hour > 24 || minute > 59 {
+			return time.Time{}, s, errNoTime
+		}
+		rest = strings.Replace(rest, m[0], " ", 1)
+		hasClock = true
+	} else if p := rePeriod.FindString(rest); p != "" {
+		period = p
+		rest = strings.Replace(rest, p, " ", 1)
+	}
+
+	if !hasDate && !hasClock && period == "" {
+		return time.Time{}, s, errNoTime
+	}
+
+	switch {
+	case hasClock && period == "" && hour >= 1 && hour <= 6:
+		// 没说时段的 1~6 点一般指下午，如 "三点提醒我开会"
+		hour += 12
+	case hasClock:
+		hour = adjustHour(hour, period)
+	case period != "":
+		hour = periodDefaultHour[period]
+	}
+
+	due := time.Date(year, month, day, hour, minute, 0, 0, now.Location())
+	if !hasDate && !due.After(now) {
+		// 没说日期：没说上午下午的 12 点以内时刻先试试下午，否则顺延到明天
+		if hasClock && period == "" && hour < 12 && due.Add(12*time.Hour).After(now) {
+			due = due.Add(12 * time.Hour)
+		} else {
+			due = due.AddDate(0, 0, 1)
+		}
+	}
+	if !due.After(now) {
+		return due, rest, errPast
+	}
+	return due, rest, nil
+}
+
+// validDate 判断年月日是否真实存在（"2月30日"、"13月5日"、小月的 "31号" 都不存在）
+func validDate(year int, month time.Month, day int) bool {
+	t := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	return t.Year() == year && t.Month() == month && t.Day() == day
+}
+
+// adjustHour 按时段把 12 小时制的钟点换算为 24 小时制
+func adjustHour(hour int, period string) int {
+	switch period {
+	case "下午", "傍晚", "晚上", "夜里", "夜晚", "晚":
+		if hour < 12 {
+			return hour + 12
+		}
+		if hour == 12 && period != "下午" {
+			return 24 // 晚上 12 点即第二天 0 点
+		}
+	case "中午":
+		if hour < 3 {
+			return hour + 12
+		}
+	case "凌晨":
+		if hour == 12 {
+			return 0
+		}
+	}
+	return hour
+}
+
+// weekdayDate 计算 "周五"、"下周一"、"这周日" 对应的日期（一周从周一开始）
+// 不带修饰时指最近的一个（今天也算）
+func weekdayDate(now time.Time, modifier string, dayStr string) time.Time {
+	target := map[string]int{"一": 1, "二": 2, "三": 3, "四": 4, "五": 5, "六": 6, "日": 7, "天": 7}[dayStr]
+	if target == 0 {
+		target, _ = strconv.Atoi(dayStr)
+	}
+	today := (int(now.Weekday())+6)%7 + 1 // 周一为 1，周日为 7
+
+	switch {
+	case strings.HasPrefix(modifier, "下下"):
+		return now.AddDate(0, 0, 14-today+target)
+	case strings.HasPrefix(modifier, "下"):
+		return now.AddDate(0, 0, 7-today+target)
+	case modifier != "": // 这周、本周
+		return now.AddDate(0, 0, target-today)
+	}
+	offset := target - today
+	if offset < 0 {
+		offset += 7
+	}
+	return now.AddDate(0, 0, offset)
+}
+
+// normalizeNumbers 把时间单位前的中文数字转为阿拉伯数字，如 "下午三点半" -> "下午3点半"
+// 不像钟点的 "一点"、"两点" 保持原样
+func normalizeNumbers(s string) string {
+	var sb strings.Builder
+	last := 0
+	for _, m := range reCNNumber.FindAllStringSubmatchIndex(s, -1) {
+		number, unit := s[m[2]:m[3]], s[m[4]:m[5]]
+		if unit == "点" && (number == "一" || number == "两") && !isClockContext(s[:m[0]], s[m[1]:]) {
+			continue
+		}
+		sb.WriteString(s[last:m[0]])
+		sb.WriteString(strconv.Itoa(chineseToInt(number)) + unit)
+		last = m[1]
+	}
+	sb.WriteString(s[last:])
+	return sb.String()
+}
+
+// isClockContext 判断 "一点"、"两点" 前后的文字是否表明这是钟点
+func isClockContext(before, after string) bool {
+	return reClockBefore.MatchString(before) || reClockAfter.MatchString(after)
+}
+
+// chineseToInt 解析 99 以内的中文数字（"十五"、"二十"、"两"），或逐位读的年份（"二零二六"）
+func chineseToInt(s string) int {
+	digits := map[rune]int{'零': 0, '〇': 0, '一': 1, '二': 2, '两': 2, '三': 3, '四': 4, '五': 5, '六': 6, '七': 7, '八': 8, '九': 9}
+	if tens, ones, ok := strings.Cut(s, "十"); ok {
+		t, o := 1, 0
+		if tens != "" {
+			t = digits[[]rune(tens)[0]]
+		}
+		if ones != "" {
+			o = digits[[]rune(ones)[0]]
+		}
+		return t*10 + o
+	}
+	n := 0
+	for _, r := range s {
+		n = n*10 + digits[r]
+	}
+	return n
+}
+
+// cleanContent 去掉消息中的 @ 和对小牛的称呼
+func cleanContent(content string) string {
+	content = reMention.ReplaceAllString(content, " ")
+	return strings.ReplaceAll(content, "小牛", " ")
+}
+
+// cleanTask 整理提醒内容：去掉多余的空白、标点和语气词
+func cleanTask(s string) string {
+	s = strings.Join(strings.Fields(strings.ReplaceAll(s, "的时候", " ")), " ")
+	s = strings.Trim(s, " ,，.。!！~～?？、")
+	for _, prefix := range []string{"记得", "我要", "要", "去", "该"} {
+		s = strings.TrimPrefix(s, prefix)
+	}
+	return strings.Trim(s, " ,，.。!！~～?？、")
+}
@@ -0,0 +1,148 @@
+package reminder
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// 2026-10-18 是周日
+var testNow = time.Date(2026, 10, 18, 10, 0, 0, 0, time.FixedZone("CST", 8*3600))
+
+func at(month time.Month, day, hour, minute int) time.Time {
+	return time.Date(2026, month, day, hour, minute, 0, 0, testNow.Location())
+}
+
+func TestParseReminder(t *testing.T) {
+	tests := []struct {
+		content string
+		due     time.Time
+		task    string
+	}{
+		{"明天下午三点提醒我交报告", at(10, 19, 15, 0), "交报告"},
+		{"小牛 十分钟后提醒我关火", at(10, 18, 10, 10), "关火"},
+		{"半小时后提醒我收衣服", at(10, 18, 10, 30), "收衣服"},
+		{"1个半小时后提醒我出门", at(10, 18, 11, 30), "出门"},
+		{"两个小时后提醒我喝一点水", at(10, 18, 12, 0), "喝一点水"},
+		{"周五晚上8点提醒我看电影", at(10, 23, 20, 0), "看电影"},
+		{"11月3号提醒我交房租", at(11, 3, 9, 0), "交房租"},
+		{"今晚提醒我吃药", at(10, 18, 20, 0), "吃药"},
+		{"提醒我15:30开会", at(10, 18, 15, 30), "开会"},
+		{"3点提醒我拿快递", at(10, 18, 15, 0), "拿快递"},
+		{"早上7点提醒我跑步", at(10, 19, 7, 0), "跑步"},
+		{"九点一刻叫我", at(10, 18, 21, 15), ""},
+
+		// "一点"、"两点" 作为钟点
+		{"一点提醒我开会", at(10, 18, 13, 0), "开会"},
+		{"下午一点提醒我开会", at(10, 18, 13, 0), "开会"},
+		{"明天一点半提醒我取快递", at(10, 19, 13, 30), "取快递"},
+		{"提醒我两点十分开会", at(10, 18, 14, 10), "开会"},
+		{"周一两点钟提醒我交作业", at(10, 19, 14, 0), "交作业"},
+		{"提醒我 一点 吃饭", at(10, 18, 13, 0), "吃饭"},
+		{"明天提醒我多带一点钱", at(10, 19, 9, 0), "多带一点钱"},
+	}
+	for _, tt := range tests {
+		due, task, err := parseReminder(tt.content, testNow)
+		if err != nil {
+			t.Errorf("parseReminder(%q) 出错: %v", tt.content, err)
+			continue
+		}
+		if !due.Equal(tt.due) || task != tt.task {
+			t.Errorf("parseReminder(%q) = %s %q，期望 %s %q", tt.content, due.Format("01-02 15:04"), task, tt.due.Format("01-02 15:04"), tt.task)
+		}
+	}
+}
+
+func TestParseReminderNoTime(t *testing.T) {
+	tests := []string{
+		"提醒我喝一点水",
+		"小牛提醒我多吃一点",
+		"提醒我早一点睡",
+		"提醒我买两点东西",
+		"小牛你能提醒我吗",
+	}
+	for _, content := range tests {
+		if due, task, err := parseReminder(content, testNow); !errors.Is(err, errNoTime) {
+			t.Errorf("parseReminder(%q) = %s %q %v，期望没有识别到时间", content, due.Format("01-02 15:04"), task, err)
+		}
+	}
+}
+
+func TestParseReminderPast(t *testing.T) {
+	if _, _, err := parseReminder("今天早上8点提醒我吃早饭", testNow); !errors.Is(err, errPast) {
+		t.Errorf("期望时间已经过去，得到 %v", err)
+	}
+}
+
+func TestParseReminderBadDate(t *testing.T) {
+	tests := []struct {
+		content string
+		now     time.Time
+	}{
+		{"2月30日提醒我交房租", testNow},
+		{"2027年2月29日提醒我交房租", testNow},
+		{"2026年13月5日提醒我交房租", testNow},
+		{"0号提醒我交房租", testNow},
+		{"31号提醒我交房租", at(11, 18, 10, 0)}, // 11 月只有 30 天
+	}
+	for _, tt := range tests {
+		if due, task, err := parseReminder(tt.content, tt.now); !errors.Is(err, errBadDate) {
+			t.Errorf("parseReminder(%q) = %s %q %v，期望日期不存在", tt.content, due.Format("2006-01-02 15:04"), task, err)
+		}
+	}
+
+	// 跨月、跨年后的日期仍然有效
+	valid := []struct {
+		content string
+		now     time.Time
+		due     time.Time
+	}{
+		{"31号提醒我交房租", testNow, at(10, 31, 9, 0)},
+		{"5号提醒我交房租", at(12, 20, 10, 0), time.Date(2027, 1, 5, 9, 0, 0, 0, testNow.Location())},
+		{"2028年2月29日提醒我交房租", testNow, time.Date(2028, 2, 29, 9, 0, 0, 0, testNow.Location())},
+	}
+	for _, tt := range valid {
+		due, _, err := parseReminder(tt.content, tt.now)
+		if err != nil || !due.Equal(tt.due) {
+			t.Errorf("parseReminder(%q) = %s %v，期望 %s", tt.content, due.Format("2006-01-02 15:04"), err, tt.due.Format("2006-01-02 15:04"))
+		}
+	}
+}
+
+func TestParseReminderTooFar(t *testing.T) {
+	for _, content := range []string{
+		"99999999天后提醒我还钱",
+		"99999999999999999999分钟后提醒我还钱",
+		"600天后提醒我还钱",
+		"100周后提醒我还钱",
+	} {
+		if due, task, err := parseReminder(content, testNow); !errors.Is(err, errTooFar) {
+			t.Errorf("parseReminder(%q) = %s %q %v，期望时间太远", content, due.Format("2006-01-02 15:04"), task, err)
+		}
+	}
+	if due, _, err := parseReminder("365天后提醒我还钱", testNow); err != nil || !due.Equal(testNow.AddDate(0, 0, 365)) {
+		t.Errorf("365 天后应当可以设置，得到 %s %v", due.Format("2006-01-02 15:04"), err)
+	}
+}
+
+func TestIsReminderRequest(t *testing.T) {
+	tests := []struct {
+		content string
+		want    bool
+	}{
+		{"小牛 明天下午三点提醒我交报告", true},
+		{"十点叫我起床", true},
+		{"过一会儿提醒我喝水", true},
+		{"小牛月底提醒我交房租", true},
+		{"小牛你能提醒我吗", false},
+		{"提醒我喝一点水", false},
+		{"小牛提醒我多吃一点", false},
+		{"你可以叫我小明", false},
+		{"小牛你还记得我吗", false},
+	}
+	for _, tt := range tests {
+		if got := isReminderRequest(tt.content, testNow); got != tt.want {
+			t.Errorf("isReminderRequest(%q) = %v，期望 %v", tt.content, got, tt.want)
+		}
+	}
+}
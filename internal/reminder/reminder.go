@@ -0,0 +1,219 @@
+package reminder
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"QQBot/internal/common"
+	"QQBot/internal/storage"
+)
+
+const (
+	reminderDataName = "reminders"      // 提醒的存储名称
+	reminderTick     = 10 * time.Second // 检查到期提醒的周期
+	reminderGrace    = 2 * time.Minute  // 超过提醒时间此时长才发出（如离线期间），附上说明
+	maxPerUser       = 20               // 每人最多同时设置的提醒数
+	maxAhead         = 366 * 24 * time.Hour
+)
+
+// Reminder 一条提醒
+type Reminder struct {
+	ID      int       `json:"id"`
+	UserID  int64     `json:"user_id"`
+	GroupID int64     `json:"group_id"` // 在群里设置的提醒发回该群，私聊设置的为 0
+	Content string    `json:"content"`
+	Due     time.Time `json:"due"`
+	Created time.Time `json:"created"`
+}
+
+// reminderState 持久化的提醒列表
+type reminderState struct {
+	NextID    int         `json:"next_id"`
+	Reminders []*Reminder `json:"reminders"`
+}
+
+var (
+	reminderMu sync.Mutex
+	state      *reminderState
+	startOnce  sync.Once
+	loop       *common.TickLoop
+)
+
+// Start 启动到期提醒检查（程序启动时调用一次），离线期间到期的提醒会在上线后补发
+func Start() {
+	startOnce.Do(func() {
+		loop = common.StartTickLoop(reminderTick, true, fireDue)
+	})
+}
+
+// Stop 停止到期提醒检查并等待正在执行的一轮结束（程序退出前调用）
+func Stop() {
+	loop.Stop()
+}
+
+// add 添加提醒
+func add(r Reminder) (Reminder, error) {
+	if r.Due.After(time.Now().Add(maxAhead)) {
+		return Reminder{}, errTooFar
+	}
+
+	reminderMu.Lock()
+	defer reminderMu.Unlock()
+	loadStateLocked()
+
+	count := 0
+	for _, existing := range state.Reminders {
+		if existing.UserID == r.UserID {
+			count++
+		}
+	}
+	if count >= maxPerUser {
+		return Reminder{}, fmt.Errorf("你已经有 %d 个提醒啦，先取消一些再来吧", count)
+	}
+
+	state.NextID++
+	r.ID = state.NextID
+	r.Created = time.Now()
+	state.Reminders = append(state.Reminders, &r)
+	saveStateLocked()
+
+	log.Printf("[提醒] 添加 #%d: 用户:%d 群:%d 时间:%s 内容:%s", r.ID, r.UserID, r.GroupID, r.Due.Format(time.RFC3339), r.Content)
+	return r, nil
+}
+
+// cancel 取消提醒，只有本人（或主人）可以取消
+func cancel(id int, userID int64) bool {
+	reminderMu.Lock()
+	defer reminderMu.Unlock()
+	loadStateLocked()
+
+	for i, r := range state.Reminders {
+		if r.ID == id && (r.UserID == userID || userID == common.MasterQQNumber) {
+			state.Reminders = append(state.Reminders[:i], state.Reminders[i+1:]...)
+			saveStateLocked()
+			log.Printf("[提醒] 取消 #%d", id)
+			return true
+		}
+	}
+	return false
+}
+
+// listOf 按时间顺序返回用户的所有提醒
+func listOf(userID int64) []Reminder {
+	reminderMu.Lock()
+	defer reminderMu.Unlock()
+	loadStateLocked()
+
+	var list []Reminder
+	for _, r := range state.Reminders {
+		if r.UserID == userID {
+			list = append(list, *r)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Due.Before(list[j].Due) })
+	return list
+}
+
+// fireDue 发出所有到期的提醒，未连接 NapCat 时等连接后再发
+func fireDue(now time.Time) {
+	if common.GetWebSocketConn() == nil {
+		return
+	}
+
+	reminderMu.Lock()
+	loadStateLocked()
+
+	var due []Reminder
+	kept := state.Reminders[:0]
+	for _, r := range state.Reminders {
+		if r.Due.After(now) {
+			kept = append(kept, r)
+			continue
+		}
+		due = append(due, *r)
+	}
+	state.Reminders = kept
+	if len(due) > 0 {
+		saveStateLocked()
+	}
+	reminderMu.Unlock()
+
+	for _, r := range due {
+		send(r, now)
+	}
+}
+
+// send 在设置提醒的地方发出提醒，群聊中 @ 本人（提醒内容来自用户，按 CQ 文本转义）
+func send(r Reminder, now time.Time) {
+	text := "⏰ 提醒你：" + common.EscapeCQText(r.Content)
+	if late := now.Sub(r.Due); late > reminderGrace {
+		text += fmt.Sprintf("\n（小牛刚才不在，晚了 %s，对不起～）", formatDuration(late))
+	}
+
+	if r.GroupID != 0 {
+		common.SendReply(common.QQEvent{MsgType: "group", GroupID: r.GroupID}, fmt.Sprintf("[CQ:at,qq=%d] %s", r.UserID, text))
+	} else {
+		common.SendPrivateMessage(r.UserID, text)
+	}
+	log.Printf("[提醒] 已发出 #%d: 用户:%d 群:%d", r.ID, r.UserID, r.GroupID)
+}
+
+// formatDuration 把时长格式化为 "3 小时 5 分钟" 这样的中文
+func formatDuration(d time.Duration) string {
+	d = d.Round(time.Minute)
+	days := int(d / (24 * time.Hour))
+	hours := int(d % (24 * time.Hour) / time.Hour)
+	minutes := int(d % time.Hour / time.Minute)
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%d 天 %d 小时", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%d 小时 %d 分钟", hours, minutes)
+	}
+	return fmt.Sprintf("%d 分钟", minutes)
+}
+
+// formatDue 格式化提醒时间，如 "明天 周一 15:00"、"10月25日 周日 09:00"
+func formatDue(due time.Time) string {
+	now := time.Now().In(common.Location)
+	due = due.In(common.Location)
+	weekday := []string{"周日", "周一", "周二", "周三", "周四", "周五", "周六"}[due.Weekday()]
+
+	y1, m1, d1 := now.Date()
+	y2, m2, d2 := due.Date()
+	days := int(time.Date(y2, m2, d2, 0, 0, 0, 0, common.Location).Sub(time.Date(y1, m1, d1, 0, 0, 0, 0, common.Location)).Hours() / 24)
+
+	var day string
+	switch {
+	case days == 0:
+		day = "今天"
+	case days == 1:
+		day = "明天"
+	case days == 2:
+		day = "后天"
+	case y1 == y2:
+		day = fmt.Sprintf("%d月%d日", m2, d2)
+	default:
+		day = fmt.Sprintf("%d年%d月%d日", y2, m2, d2)
+	}
+	return fmt.Sprintf("%s %s %s", day, weekday, due.Format("15:04"))
+}
+
+// loadStateLocked 首次使用时从存储加载
+func loadStateLocked() {
+	if state != nil {
+		return
+	}
+	state = &reminderState{}
+	storage.LoadData(reminderDataName, state)
+}
+
+// saveStateLocked 保存到存储
+func saveStateLocked() {
+	if err := storage.SaveData(reminderDataName, state); err != nil {
+		log.Printf("[提醒] 保存失败: %v", err)
+	}
+}
@@ -0,0 +1,102 @@
+package request
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"QQBot/internal/common"
+)
+
+// HandleRequestEvent 处理好友/群请求：符合策略的自动同意，其余转交主人审批
+func HandleRequestEvent(req common.RequestEvent) {
+	if req.Flag == "" {
+		return
+	}
+	log.Printf("[请求] <- 类型:%s/%s 用户:%d 群:%d 验证:%s", req.RequestType, req.SubType, req.UserID, req.GroupID, req.Comment)
+
+	if reason := autoApproveReason(req); reason != "" {
+		log.Printf("[请求] 自动同意（%s）: 用户:%d 群:%d", reason, req.UserID, req.GroupID)
+		setRequest(req, true, "")
+		return
+	}
+
+	if common.MasterQQNumber == 0 {
+		log.Printf("[请求] 未设置 MASTER_QQ，请求保持待处理: 用户:%d 群:%d", req.UserID, req.GroupID)
+		return
+	}
+
+	code, ok := addPending(req)
+	if !ok {
+		log.Printf("[请求] 待审批请求过多，不再转交主人，请求保持待处理: 用户:%d 群:%d", req.UserID, req.GroupID)
+		return
+	}
+	common.SendPrivateMessage(common.MasterQQNumber, formatPendingNotice(code, req))
+}
+
+// autoApproveReason 判断请求是否可自动同意，返回同意原因（空字符串表示需要人工审批）
+func autoApproveReason(req common.RequestEvent) string {
+	// 主人本人发起的请求（加好友、邀请入群）直接同意
+	if req.UserID == common.MasterQQNumber && common.MasterQQNumber > 0 {
+		return "主人发起"
+	}
+	if containsInt64(common.RequestUserWhitelist, req.UserID) {
+		return "用户白名单"
+	}
+	if req.RequestType == "group" && containsInt64(common.RequestGroupWhitelist, req.GroupID) {
+		return "群白名单"
+	}
+	for _, keyword := range common.RequestKeywords {
+		if strings.Contains(req.Comment, keyword) {
+			return "验证消息关键词"
+		}
+	}
+	return ""
+}
+
+// setRequest 调用 OneBot 接口同意或拒绝请求
+func setRequest(req common.RequestEvent, approve bool, reason string) bool {
+	if req.RequestType == "friend" {
+		return common.SendAction("set_friend_add_request", map[string]interface{}{
+			"flag":    req.Flag,
+			"approve": approve,
+		})
+	}
+	return common.SendAction("set_group_add_request", map[string]interface{}{
+		"flag":     req.Flag,
+		"sub_type": req.SubType,
+		"approve":  approve,
+		"reason":   reason,
+	})
+}
+
+// describeRequest 生成请求的简短描述
+func describeRequest(req common.RequestEvent) string {
+	switch {
+	case req.RequestType == "friend":
+		return fmt.Sprintf("%d 申请加小牛为好友", req.UserID)
+	case req.SubType == "invite":
+		return fmt.Sprintf("%d 邀请小牛加入群 %d", req.UserID, req.GroupID)
+	default:
+		return fmt.Sprintf("%d 申请加入群 %d", req.UserID, req.GroupID)
+	}
+}
+
+// formatPendingNotice 生成发给主人的待审批通知
+func formatPendingNotice(code string, req common.RequestEvent) string {
+	comment := req.Comment
+	if comment == "" {
+		comment = "（无）"
+	}
+	return fmt.Sprintf("爸爸，有新的请求需要你处理哦～\n[%s] %s\n验证消息：%s\n回复“同意 %s”或“拒绝 %s [理由]”即可",
+		code, describeRequest(req), comment, code, code)
+}
+
+func containsInt64(list []int64, v int64) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
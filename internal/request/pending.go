@@ -0,0 +1,149 @@
+package request
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"QQBot/internal/common"
+	"QQBot/internal/storage"
+)
+
+const (
+	pendingDataName = "pending_requests" // 待审批请求的存储名称
+	pendingExpiry   = 72 * time.Hour     // 超过此时间的请求视为过期（NapCat 端 flag 也会失效）
+	pendingMax      = 200                // 最多同时等待审批的请求数，远小于短码数量，保证总能分配到短码
+)
+
+// pendingRequest 等待主人审批的请求
+type pendingRequest struct {
+	Code    string              `json:"code"`
+	Request common.RequestEvent `json:"request"`
+	Time    string              `json:"time"`
+}
+
+var (
+	pendingMu       sync.Mutex
+	pendingRequests map[string]*pendingRequest // 短码 -> 请求
+)
+
+// addPending 记录待审批请求，返回分配的短码；待审批的请求过多时返回 false
+func addPending(req common.RequestEvent) (string, bool) {
+	pendingMu.Lock()
+	defer pendingMu.Unlock()
+	loadPendingLocked()
+
+	// 同一 flag 重复推送时沿用原短码
+	for code, p := range pendingRequests {
+		if p.Request.Flag == req.Flag {
+			return code, true
+		}
+	}
+
+	if pruneExpiredLocked() {
+		savePendingLocked()
+	}
+	if len(pendingRequests) >= pendingMax {
+		return "", false
+	}
+
+	code := newCodeLocked()
+	pendingRequests[code] = &pendingRequest{
+		Code:    code,
+		Request: req,
+		Time:    time.Now().Format(time.RFC3339),
+	}
+	savePendingLocked()
+	return code, true
+}
+
+// findPending 查找指定短码的请求
+func findPending(code string) (*pendingRequest, bool) {
+	pendingMu.Lock()
+	defer pendingMu.Unlock()
+	loadPendingLocked()
+
+	p, ok := pendingRequests[code]
+	return p, ok
+}
+
+// removePending 移除已处理的请求
+func removePending(code string) {
+	pendingMu.Lock()
+	defer pendingMu.Unlock()
+	loadPendingLocked()
+
+	if _, ok := pendingRequests[code]; ok {
+		delete(pendingRequests, code)
+		savePendingLocked()
+	}
+}
+
+// listPending 按时间顺序列出所有待审批请求
+func listPending() []*pendingRequest {
+	pendingMu.Lock()
+	defer pendingMu.Unlock()
+	loadPendingLocked()
+
+	list := make([]*pendingRequest, 0, len(pendingRequests))
+	for _, p := range pendingRequests {
+		list = append(list, p)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Time < list[j].Time })
+	return list
+}
+
+// newCodeLocked 生成一个未被占用的 4 位数字短码（调用方保证待审批请求不超过 pendingMax）
+func newCodeLocked() string {
+	for {
+		code := fmt.Sprintf("%04d", rand.Intn(10000))
+		if _, exists := pendingRequests[code]; !exists {
+			return code
+		}
+	}
+}
+
+// loadPendingLocked 首次使用时加载待审批请求，并清理过期项
+func loadPendingLocked() {
+	if pendingRequests != nil {
+		return
+	}
+	pendingRequests = make(map[string]*pendingRequest)
+
+	var list []*pendingRequest
+	if !storage.LoadData(pendingDataName, &list) {
+		// 不存在是正常的
+		return
+	}
+
+	for _, p := range list {
+		pendingRequests[p.Code] = p
+	}
+	pruneExpiredLocked()
+}
+
+// pruneExpiredLocked 清理过期的请求，返回是否有清理
+func pruneExpiredLocked() bool {
+	pruned := false
+	for code, p := range pendingRequests {
+		if t, err := time.Parse(time.RFC3339, p.Time); err == nil && time.Since(t) > pendingExpiry {
+			delete(pendingRequests, code)
+			pruned = true
+		}
+	}
+	return pruned
+}
+
+// savePendingLocked 保存待审批请求
+func savePendingLocked() {
+	list := make([]*pendingRequest, 0, len(pendingRequests))
+	for _, p := range pendingRequests {
+		list = append(list, p)
+	}
+	if err := storage.SaveData(pendingDataName, list); err != nil {
+		log.Printf("[请求] 保存失败: %v", err)
+	}
+}
@@ -0,0 +1,27 @@
+package request
+
+import (
+	"context"
+
+	"QQBot/internal/common"
+	"QQBot/internal/handler"
+)
+
+func init() {
+	handler.Register(reviewHandler{})
+}
+
+// reviewHandler 主人私聊审批好友/群请求
+type reviewHandler struct{}
+
+func (reviewHandler) Name() string  { return "review" }
+func (reviewHandler) Priority() int { return 20 }
+
+func (reviewHandler) Match(event common.QQEvent) bool {
+	return ShouldHandleReviewCommand(event)
+}
+
+func (reviewHandler) Handle(_ context.Context, event common.QQEvent) handler.Result {
+	HandleReviewCommand(event)
+	return handler.Stop
+}
@@ -0,0 +1,80 @@
+package request
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"QQBot/internal/common"
+)
+
+// 主人私聊审批指令
+const (
+	approveCommand = "同意"
+	rejectCommand  = "拒绝"
+	listCommand    = "待处理"
+)
+
+// ShouldHandleReviewCommand 判断是否为主人私聊发送的审批指令
+func ShouldHandleReviewCommand(event common.QQEvent) bool {
+	if event.MsgType != "private" || common.MasterQQNumber == 0 || event.UserID != common.MasterQQNumber {
+		return false
+	}
+	fields := strings.Fields(event.Content)
+	if len(fields) == 0 {
+		return false
+	}
+	switch fields[0] {
+	case approveCommand, rejectCommand:
+		return len(fields) >= 2
+	case listCommand:
+		return len(fields) == 1
+	}
+	return false
+}
+
+// HandleReviewCommand 处理主人的审批指令：同意 <短码> / 拒绝 <短码> [理由] / 待处理
+func HandleReviewCommand(event common.QQEvent) {
+	fields := strings.Fields(event.Content)
+
+	if fields[0] == listCommand {
+		common.SendReply(event, formatPendingList())
+		return
+	}
+
+	code := fields[1]
+	p, ok := findPending(code)
+	if !ok {
+		common.SendReply(event, fmt.Sprintf("没有找到编号为 %s 的请求，可能已经处理过或过期了", code))
+		return
+	}
+
+	approve := fields[0] == approveCommand
+	reason := strings.Join(fields[2:], " ")
+	if !setRequest(p.Request, approve, reason) {
+		common.SendReply(event, "处理失败了，连接好像断开了，等会儿再试试吧")
+		return
+	}
+	removePending(code)
+
+	log.Printf("[请求] 主人%s: [%s] %s", fields[0], code, describeRequest(p.Request))
+	common.SendReply(event, fmt.Sprintf("已%s：%s", fields[0], describeRequest(p.Request)))
+}
+
+// formatPendingList 生成待审批请求列表
+func formatPendingList() string {
+	list := listPending()
+	if len(list) == 0 {
+		return "目前没有待处理的请求～"
+	}
+
+	var sb strings.Builder
+	sb.WriteString("待处理的请求：")
+	for _, p := range list {
+		sb.WriteString(fmt.Sprintf("\n[%s] %s", p.Code, describeRequest(p.Request)))
+		if p.Request.Comment != "" {
+			sb.WriteString("（" + p.Request.Comment + "）")
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,94 @@
+package safety
+
+import "unicode"
+
+// matcher Aho-Corasick 多模式匹配自动机（按字符匹配，忽略大小写），一次扫描找出所有敏感词
+type matcher struct {
+	nodes []acNode
+	words []Word
+}
+
+type acNode struct {
+	next   map[rune]int
+	fail   int
+	output []int // 以此节点结尾的词在 words 中的下标（含经失败指针可达的）
+}
+
+// match 一次命中，Start、End 为字符（rune）下标，左闭右开
+type match struct {
+	Start, End int
+	Word       *Word
+}
+
+// newMatcher 构建自动机
+func newMatcher(words []Word) *matcher {
+	m := &matcher{nodes: []acNode{{next: make(map[rune]int)}}, words: words}
+	for i, w := range words {
+		cur := 0
+		for _, r := range w.Text {
+			r = unicode.ToLower(r)
+			nxt, ok := m.nodes[cur].next[r]
+			if !ok {
+				nxt = len(m.nodes)
+				m.nodes = append(m.nodes, acNode{next: make(map[rune]int)})
+				m.nodes[cur].next[r] = nxt
+			}
+			cur = nxt
+		}
+		if cur != 0 {
+			m.nodes[cur].output = append(m.nodes[cur].output, i)
+		}
+	}
+
+	// 按层构建失败指针
+	queue := make([]int, 0, len(m.nodes))
+	for _, child := range m.nodes[0].next {
+		queue = append(queue, child)
+	}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for r, child := range m.nodes[cur].next {
+			f := m.nodes[cur].fail
+			for f != 0 {
+				if _, ok := m.nodes[f].next[r]; ok {
+					break
+				}
+				f = m.nodes[f].fail
+			}
+			if target, ok := m.nodes[f].next[r]; ok && target != child {
+				m.nodes[child].fail = target
+			}
+			m.nodes[child].output = append(m.nodes[child].output, m.nodes[m.nodes[child].fail].output...)
+			queue = append(queue, child)
+		}
+	}
+	return m
+}
+
+// findAll 找出文本中所有命中的词（可能重叠）
+func (m *matcher) findAll(text []rune) []match {
+	if m == nil || len(m.words) == 0 {
+		return nil
+	}
+	var matches []match
+	cur := 0
+	for i, r := range text {
+		r = unicode.ToLower(r)
+		for cur != 0 {
+			if _, ok := m.nodes[cur].next[r]; ok {
+				break
+			}
+			cur = m.nodes[cur].fail
+		}
+		if nxt, ok := m.nodes[cur].next[r]; ok {
+			cur = nxt
+		}
+		for _, idx := range m.nodes[cur].output {
+			w := &m.words[idx]
+			n := len([]rune(w.Text))
+			matches = append(matches, match{Start: i + 1 - n, End: i + 1, Word: w})
+		}
+	}
+	return matches
+}
@@ -0,0 +1,46 @@
+package safety
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestMatcherFindAll(t *testing.T) {
+	words := []Word{{Text: "he"}, {Text: "she"}, {Text: "his"}, {Text: "hers"}, {Text: "敏感词"}, {Text: "感词"}, {Text: "DeepSeek"}}
+	m := newMatcher(words)
+
+	// 每个命中记为 "词[起,止)"，按扫描顺序（结尾位置）排列
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"ushers", []string{"she[1,4)", "he[2,4)", "hers[2,6)"}},
+		{"his", []string{"his[0,3)"}},
+		{"这是敏感词吗", []string{"敏感词[2,5)", "感词[3,5)"}},
+		{"用的是DEEPSEEK模型", []string{"DeepSeek[3,11)"}},
+		{"deepseek 和 SHE", []string{"DeepSeek[0,8)", "she[11,14)", "he[12,14)"}},
+		{"敏感敏感词", []string{"敏感词[2,5)", "感词[3,5)"}},
+		{"没有命中", nil},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, mt := range m.findAll([]rune(tt.text)) {
+			got = append(got, fmt.Sprintf("%s[%d,%d)", mt.Word.Text, mt.Start, mt.End))
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("findAll(%q) = %v，期望 %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestMatcherEmpty(t *testing.T) {
+	var m *matcher
+	if got := m.findAll([]rune("任何内容")); got != nil {
+		t.Errorf("nil matcher 应没有命中，得到 %v", got)
+	}
+	if got := newMatcher(nil).findAll([]rune("任何内容")); got != nil {
+		t.Errorf("空词表应没有命中，得到 %v", got)
+	}
+}
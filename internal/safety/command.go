@@ -0,0 +1,50 @@
+package safety
+
+import (
+	"fmt"
+	"strings"
+
+	"QQBot/internal/command"
+)
+
+func init() {
+	command.Register(&command.Command{
+		Name:        "safety",
+		Aliases:     []string{"安全"},
+		Description: "管理 AI 回复的安全过滤",
+		Permission:  command.PermMaster,
+		Subcommands: []*command.Command{
+			{Name: "reload", Aliases: []string{"重载"}, Description: "重新读取敏感词文件", Run: runReload},
+			{
+				Name: "test", Aliases: []string{"测试"},
+				Description: "测试一段文字会被如何处理",
+				Args:        []command.Arg{{Name: "文字", Type: command.ArgRest}},
+				Run:         runTest,
+			},
+		},
+	})
+}
+
+func runReload(ctx *command.Context) error {
+	n, err := Reload()
+	if err != nil {
+		ctx.Reply(fmt.Sprintf("读取敏感词文件失败：%v", err))
+		return nil
+	}
+	ctx.Reply(fmt.Sprintf("已重新加载 %d 个敏感词", n))
+	return nil
+}
+
+func runTest(ctx *command.Context) error {
+	res := Check(ctx.String("文字"))
+	var sb strings.Builder
+	sb.WriteString("处理方式：" + res.Action.String())
+	if len(res.Reasons) > 0 {
+		sb.WriteString("\n原因：" + strings.Join(res.Reasons, "；"))
+	}
+	if res.Action == Pass || res.Action == Mask {
+		sb.WriteString("\n发送内容：" + res.Text)
+	}
+	ctx.Reply(sb.String())
+	return nil
+}
@@ -0,0 +1,298 @@
+package safety
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+
+	"QQBot/internal/common"
+)
+
+// Policy 命中后的处理策略
+type Policy int
+
+// 按严重程度从低到高排列，多处命中时取最严重的
+const (
+	Pass       Policy = iota // 没有问题
+	Mask                     // 用 * 遮住命中的词
+	Regenerate               // 让 AI 重新生成
+	Refuse                   // 不发送原回复，改为固定的拒绝语
+)
+
+const (
+	RefusalText = "这个话题小牛不方便聊哦，换个话题吧～" // Refuse 时发送的内容
+
+	leakWindow = 16 // 回复中连续出现系统提示词的这么多个字（不计标点空白）视为泄露，太短容易误判常见说法
+	leakStep   = 8  // 切分系统提示词时窗口的步长，连续出现 leakWindow+leakStep 个字时一定能检测到
+)
+
+var policyNames = map[string]Policy{"mask": Mask, "regenerate": Regenerate, "refuse": Refuse}
+
+// String 策略名，用于日志
+func (p Policy) String() string {
+	switch p {
+	case Mask:
+		return "mask"
+	case Regenerate:
+		return "regenerate"
+	case Refuse:
+		return "refuse"
+	}
+	return "pass"
+}
+
+// ParsePolicy 解析策略名（mask/regenerate/refuse）
+func ParsePolicy(s string) (Policy, bool) {
+	p, ok := policyNames[strings.ToLower(strings.TrimSpace(s))]
+	return p, ok
+}
+
+// Word 一个敏感词及其策略
+type Word struct {
+	Text   string
+	Policy Policy
+}
+
+// Result 检查结果
+type Result struct {
+	Text    string   // 处理后的文本（已去掉旁白，Mask 时已遮住敏感词）
+	Action  Policy   // 调用方需要采取的动作
+	Reasons []string // 命中的原因，用于日志
+}
+
+var (
+	filterMu      sync.Mutex
+	wordMatcher   *matcher
+	wordsLoaded   bool
+	leakFragments []string // 归一化后的系统提示词片段
+	leakMarkers   []string // 系统提示词中的小节标题，如 "身份判别准则"
+
+	// 旁白/动作描写：括号内的一小段话
+	reStageDirection = regexp.MustCompile(`[（(]([^（）()\n]{1,40})[）)]|\*([^*\n]{1,30})\*`)
+	// 系统提示词中的小节标题，如 "【回复原则】："
+	reSectionHeader = regexp.MustCompile(`【([^】]{2,10})】[：:（(]`)
+)
+
+// stageVerbs 括号里出现这些词时视为动作描写，如 "（歪头）"、"（小声）"
+var stageVerbs = []string{"看", "笑", "歪头", "眨", "摸", "点头", "摇头", "叹气", "小声", "偷偷", "吐舌", "托腮", "挠", "脸红", "抱", "蹭", "跺脚", "撅嘴", "嘟嘴", "捂", "拍", "转圈", "跳", "哼", "噘嘴", "思考", "停顿", "沉默"}
+
+// ProtectPrompt 登记系统提示词，回复中出现其中的原句或小节标题时视为泄露（由 AI 模块在初始化时调用）
+func ProtectPrompt(texts ...string) {
+	filterMu.Lock()
+	defer filterMu.Unlock()
+	for _, text := range texts {
+		for _, m := range reSectionHeader.FindAllStringSubmatch(text, -1) {
+			leakMarkers = append(leakMarkers, m[1])
+		}
+		for _, sentence := range strings.FieldsFunc(text, func(r rune) bool {
+			return r == '\n' || r == '。' || r == '！' || r == '？' || r == '；'
+		}) {
+			leakFragments = append(leakFragments, leakWindows(normalizeForLeak(sentence))...)
+		}
+	}
+}
+
+// Check 检查 AI 的回复：去掉旁白，检测系统提示词泄露和敏感词，返回处理后的文本和需要采取的动作
+func Check(text string) Result {
+	res := Result{Text: text}
+	if common.SafetyStripStage {
+		if stripped := stripStageDirections(text); stripped != text {
+			res.Text = stripped
+			res.Reasons = append(res.Reasons, "去掉旁白")
+		}
+		if strings.TrimSpace(res.Text) == "" {
+			res.Action = Regenerate
+			res.Reasons = append(res.Reasons, "只有旁白")
+			return res
+		}
+	}
+
+	filterMu.Lock()
+	loadWordsLocked()
+	m := wordMatcher
+	leaked := detectLeakLocked(res.Text)
+	filterMu.Unlock()
+
+	if leaked != "" {
+		res.Action, _ = ParsePolicy(common.SafetyLeakPolicy)
+		res.Reasons = append(res.Reasons, "疑似泄露系统提示词: "+leaked)
+	}
+
+	runes := []rune(res.Text)
+	matches := m.findAll(runes)
+	masked := false
+	for _, mt := range matches {
+		res.Reasons = append(res.Reasons, fmt.Sprintf("敏感词 %q(%s)", mt.Word.Text, mt.Word.Policy))
+		if mt.Word.Policy > res.Action {
+			res.Action = mt.Word.Policy
+		}
+		if mt.Word.Policy == Mask {
+			for i := mt.Start; i < mt.End; i++ {
+				runes[i] = '*'
+			}
+			masked = true
+		}
+	}
+	if masked {
+		res.Text = string(runes)
+	}
+	return res
+}
+
+// Reload 重新读取敏感词文件，返回词数
+func Reload() (int, error) {
+	filterMu.Lock()
+	defer filterMu.Unlock()
+	wordsLoaded = false
+	wordMatcher = nil
+	return loadWordsLocked()
+}
+
+// loadWordsLocked 首次使用时读取敏感词文件
+// 每行一个词，可用 "词|策略" 指定策略（mask/regenerate/refuse），未指定时使用 SAFETY_DEFAULT_POLICY；# 开头为注释
+func loadWordsLocked() (int, error) {
+	if wordsLoaded {
+		return len(wordMatcher.words), nil
+	}
+	wordsLoaded = true
+	wordMatcher = newMatcher(nil)
+
+	f, err := os.Open(common.SafetyWordsFile)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			log.Printf("[安全] 敏感词文件 %s 不存在，不检查敏感词", common.SafetyWordsFile)
+			return 0, nil
+		}
+		log.Printf("[安全] 读取敏感词文件失败: %v", err)
+		return 0, err
+	}
+	defer f.Close()
+
+	defaultPolicy, _ := ParsePolicy(common.SafetyDefaultPolicy)
+	var words []Word
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(f)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		w := Word{Text: line, Policy: defaultPolicy}
+		if text, policy, ok := strings.Cut(line, "|"); ok {
+			p, valid := ParsePolicy(policy)
+			if !valid {
+				log.Printf("[安全] 敏感词文件第 %d 行的策略 %q 无效，使用默认策略", lineNo, policy)
+				p = defaultPolicy
+			}
+			w = Word{Text: strings.TrimSpace(text), Policy: p}
+		}
+		key := strings.ToLower(w.Text)
+		if w.Text == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		words = append(words, w)
+	}
+	if err := scanner.Err(); err != nil {
+		log.Printf("[安全] 读取敏感词文件失败: %v", err)
+		return 0, err
+	}
+
+	wordMatcher = newMatcher(words)
+	log.Printf("[安全] 已加载 %d 个敏感词", len(words))
+	return len(words), nil
+}
+
+// detectLeakLocked 检测回复中是否出现系统提示词的原句或小节标题，返回命中的内容
+func detectLeakLocked(text string) string {
+	for _, marker := range leakMarkers {
+		if strings.Contains(text, marker) {
+			return marker
+		}
+	}
+	normalized := normalizeForLeak(text)
+	for _, fragment := range leakFragments {
+		if strings.Contains(normalized, fragment) {
+			return fragment
+		}
+	}
+	return ""
+}
+
+// leakWindows 把一句话切成长度为 leakWindow、互相重叠的片段，不足 leakWindow 个字的句子不参与检测
+func leakWindows(s string) []string {
+	runes := []rune(s)
+	if len(runes) < leakWindow {
+		return nil
+	}
+	var windows []string
+	for start := 0; ; start += leakStep {
+		if start+leakWindow >= len(runes) {
+			windows = append(windows, string(runes[len(runes)-leakWindow:]))
+			return windows
+		}
+		windows = append(windows, string(runes[start:start+leakWindow]))
+	}
+}
+
+// normalizeForLeak 去掉空白和标点，避免改了标点、加了空格就绕过检测
+func normalizeForLeak(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || unicode.IsPunct(r) || unicode.IsSymbol(r) {
+			return -1
+		}
+		return unicode.ToLower(r)
+	}, s)
+}
+
+// stripStageDirections 去掉人设禁止的旁白和动作描写，如 "（歪头）"、"(看到消息后)"、"*眨眨眼*"
+// 只去掉包含动作词的括号和星号，保留 "（周末延长到 24 点）" 这类补充说明和 Markdown 的 *强调*、**加粗**
+func stripStageDirections(text string) string {
+	lines := strings.Split(text, "\n")
+	stripped := false
+	for i, line := range lines {
+		locs := reStageDirection.FindAllStringSubmatchIndex(line, -1)
+		if len(locs) == 0 {
+			continue
+		}
+		var sb strings.Builder
+		last := 0
+		for _, loc := range locs {
+			start, end := loc[0], loc[1]
+			if !containsAny(line[start:end], stageVerbs) {
+				continue
+			}
+			// **加粗** 中间的一对星号不算动作描写
+			if line[start] == '*' && (start > 0 && line[start-1] == '*' || end < len(line) && line[end] == '*') {
+				continue
+			}
+			sb.WriteString(line[last:start])
+			last = end
+		}
+		if last == 0 {
+			continue
+		}
+		sb.WriteString(line[last:])
+		lines[i] = strings.TrimSpace(sb.String())
+		stripped = true
+	}
+	if !stripped {
+		return text
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+func containsAny(s string, words []string) bool {
+	for _, w := range words {
+		if strings.Contains(s, w) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,148 @@
+package schedule
+
+import (
+	"fmt"
+	"strings"
+
+	"QQBot/internal/command"
+)
+
+const listContentMaxRune = 30 // 列出任务时内容最多显示的字符数
+
+// 目标类型和模式的中文别名
+var (
+	targetAliases = map[string]string{
+		"group": "group", "群": "group", "群聊": "group",
+		"private": "private", "私聊": "private", "user": "private",
+	}
+	modeAliases = map[string]string{
+		"text": ModeText, "文本": ModeText,
+		"ai": ModeAI, "AI": ModeAI,
+		"summary": ModeSummary, "总结": ModeSummary,
+	}
+	modeNames = map[string]string{
+		ModeText:    "文本",
+		ModeAI:      "AI生成",
+		ModeSummary: "群聊总结",
+	}
+)
+
+func init() {
+	command.Register(&command.Command{
+		Name:        "cron",
+		Aliases:     []string{"定时"},
+		Description: "管理定时任务",
+		Permission:  command.PermMaster,
+		Subcommands: []*command.Command{
+			{
+				Name: "add", Aliases: []string{"添加"},
+				Description: "添加任务，时间为 cron 表达式（需加引号，如 \"0 8 * * *\"），模式为 text/ai/summary",
+				Args: []command.Arg{
+					{Name: "群/私聊"},
+					{Name: "号码", Type: command.ArgQQ},
+					{Name: "时间"},
+					{Name: "模式"},
+					{Name: "内容", Type: command.ArgRest, Optional: true},
+				},
+				Run: runAdd,
+			},
+			{Name: "list", Aliases: []string{"列表"}, Description: "查看所有任务", Run: runList},
+			{Name: "remove", Aliases: []string{"删除"}, Description: "删除任务", Args: []command.Arg{{Name: "编号", Type: command.ArgInt}}, Run: runRemove},
+			{Name: "run", Aliases: []string{"执行"}, Description: "立即执行一次任务", Args: []command.Arg{{Name: "编号", Type: command.ArgInt}}, Run: runRun},
+		},
+	})
+}
+
+func runAdd(ctx *command.Context) error {
+	targetType, ok := targetAliases[ctx.String("群/私聊")]
+	if !ok {
+		return command.Usagef("目标应为 群 或 私聊")
+	}
+	mode, ok := modeAliases[ctx.String("模式")]
+	if !ok {
+		return command.Usagef("模式应为 text（文本）、ai（AI 生成）或 summary（群聊总结）")
+	}
+	content := ctx.String("内容")
+	if mode != ModeSummary && content == "" {
+		return command.Usagef("text 和 ai 模式需要填写内容")
+	}
+	if mode == ModeSummary && targetType != "group" {
+		return command.Usagef("群聊总结只能发到群里")
+	}
+
+	job, err := AddJob(Job{
+		Spec:       ctx.String("时间"),
+		TargetType: targetType,
+		TargetID:   ctx.QQ("号码"),
+		Mode:       mode,
+		Content:    content,
+		Creator:    ctx.Event.UserID,
+	})
+	if err != nil {
+		return command.Usagef("%v", err)
+	}
+	ctx.Reply(fmt.Sprintf("已添加定时任务 #%d，下次执行：%s", job.ID, formatNext(job)))
+	return nil
+}
+
+func runList(ctx *command.Context) error {
+	jobs := Jobs()
+	if len(jobs) == 0 {
+		ctx.Reply("还没有定时任务")
+		return nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("定时任务（%d 个）：", len(jobs)))
+	for _, job := range jobs {
+		target := fmt.Sprintf("群%d", job.TargetID)
+		if job.TargetType == "private" {
+			target = fmt.Sprintf("私聊%d", job.TargetID)
+		}
+		sb.WriteString(fmt.Sprintf("\n#%d [%s] %s %s", job.ID, job.Spec, target, modeNames[job.Mode]))
+		if job.Content != "" {
+			sb.WriteString("：" + truncateRunes(job.Content, listContentMaxRune))
+		}
+		sb.WriteString("\n    下次：" + formatNext(job))
+	}
+	ctx.Reply(sb.String())
+	return nil
+}
+
+func runRemove(ctx *command.Context) error {
+	id := ctx.Int("编号")
+	if !RemoveJob(id) {
+		ctx.Reply(fmt.Sprintf("没有编号为 %d 的任务", id))
+		return nil
+	}
+	ctx.Reply(fmt.Sprintf("已删除定时任务 #%d", id))
+	return nil
+}
+
+func runRun(ctx *command.Context) error {
+	id := ctx.Int("编号")
+	if !RunNow(id) {
+		ctx.Reply(fmt.Sprintf("没有编号为 %d 的任务", id))
+		return nil
+	}
+	ctx.Reply(fmt.Sprintf("已执行定时任务 #%d", id))
+	return nil
+}
+
+// formatNext 格式化下次执行时间
+func formatNext(job Job) string {
+	next := job.NextRun()
+	if next.IsZero() {
+		return "不会再执行（表达式无效或日期不存在）"
+	}
+	return next.Format("2006-01-02 15:04 MST")
+}
+
+// truncateRunes 按字符截断
+func truncateRunes(s string, max int) string {
+	r := []rune(s)
+	if len(r) <= max {
+		return s
+	}
+	return string(r[:max]) + "…"
+}
@@ -0,0 +1,190 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxSearchYears 计算下次执行时间时最多向后查找的年数（如 2 月 30 日这种永远不会到来的时间）
+const maxSearchYears = 5
+
+// 常用的简写
+var cronAliases = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// Schedule 解析后的 cron 表达式（分 时 日 月 周），每个字段用位图表示允许的取值
+type Schedule struct {
+	minute, hour, dom, month, dow uint64
+	domStar, dowStar              bool // 日、周字段是否为 *（都不是 * 时两者满足其一即可，与标准 cron 一致）
+	hourEvery                     bool // 小时字段是否为 * 或 */n，此时夏令时回拨重复的那一小时照常执行
+	loc                           *time.Location
+}
+
+// cronField 字段定义
+type cronField struct {
+	name     string
+	min, max int
+}
+
+var cronFields = []cronField{
+	{"分钟", 0, 59},
+	{"小时", 0, 23},
+	{"日期", 1, 31},
+	{"月份", 1, 12},
+	{"星期", 0, 7}, // 0 和 7 都表示周日
+}
+
+// ParseSchedule 解析 cron 表达式，如 "0 8 * * 1-5"、"*/30 9-18 * * *"、"@daily"
+// 可以用 "TZ=时区" 前缀指定时区（如 "TZ=America/New_York 0 9 * * *"），否则使用 loc
+func ParseSchedule(spec string, loc *time.Location) (*Schedule, error) {
+	fields := strings.Fields(spec)
+	if len(fields) > 0 && (strings.HasPrefix(fields[0], "TZ=") || strings.HasPrefix(fields[0], "CRON_TZ=")) {
+		_, name, _ := strings.Cut(fields[0], "=")
+		l, err := time.LoadLocation(name)
+		if err != nil {
+			return nil, fmt.Errorf("未知时区 %s", name)
+		}
+		loc = l
+		fields = fields[1:]
+	}
+	if len(fields) == 1 {
+		if expanded, ok := cronAliases[strings.ToLower(fields[0])]; ok {
+			fields = strings.Fields(expanded)
+		}
+	}
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("时间表达式应为 5 段（分 时 日 月 周），如 \"0 8 * * *\"")
+	}
+
+	var bits [5]uint64
+	for i, f := range cronFields {
+		b, err := parseCronField(fields[i], f)
+		if err != nil {
+			return nil, err
+		}
+		bits[i] = b
+	}
+	// 周字段中的 7 等同于 0（周日）
+	if bits[4]&(1<<7) != 0 {
+		bits[4] |= 1
+	}
+
+	return &Schedule{
+		minute:    bits[0],
+		hour:      bits[1],
+		dom:       bits[2],
+		month:     bits[3],
+		dow:       bits[4],
+		domStar:   fields[2] == "*",
+		dowStar:   fields[4] == "*",
+		hourEvery: strings.HasPrefix(fields[1], "*"),
+		loc:       loc,
+	}, nil
+}
+
+// parseCronField 解析单个字段：支持 *、数字、范围 a-b、步长 /n 以及逗号分隔的列表
+func parseCronField(s string, f cronField) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(s, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepPart)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("%s字段的步长 %q 无效", f.name, stepPart)
+			}
+			step = n
+		}
+
+		lo, hi := f.min, f.max
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			a, b, _ := strings.Cut(rangePart, "-")
+			var err1, err2 error
+			lo, err1 = strconv.Atoi(a)
+			hi, err2 = strconv.Atoi(b)
+			if err1 != nil || err2 != nil {
+				return 0, fmt.Errorf("%s字段的范围 %q 无效", f.name, rangePart)
+			}
+		default:
+			n, err := strconv.Atoi(rangePart)
+			if err != nil {
+				return 0, fmt.Errorf("%s字段的取值 %q 无效", f.name, rangePart)
+			}
+			lo = n
+			if !hasStep {
+				hi = n
+			}
+		}
+		if lo < f.min || hi > f.max || lo > hi {
+			return 0, fmt.Errorf("%s字段应在 %d 到 %d 之间", f.name, f.min, f.max)
+		}
+
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// Next 返回 after 之后（不含）的下一次执行时间，找不到时返回零值
+// 夏令时开始时被跳过的墙上时间不会执行；回拨时重复的那一小时只执行一次（小时字段为 * 或 */n 的除外）
+func (s *Schedule) Next(after time.Time) time.Time {
+	// 按绝对时间取整，回拨期间用 time.Date 重建墙上时间可能落到更早的那一次
+	t := after.In(s.loc).Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(maxSearchYears, 0, 0)
+
+	for t.Before(limit) {
+		var next time.Time
+		switch {
+		case s.month&(1<<uint(t.Month())) == 0:
+			next = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, s.loc)
+		case !s.dayMatches(t):
+			next = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, s.loc)
+		case s.hour&(1<<uint(t.Hour())) == 0:
+			next = t.Add(time.Duration(60-t.Minute()) * time.Minute)
+		case s.minute&(1<<uint(t.Minute())) == 0:
+			next = t.Add(time.Minute)
+		case !s.hourEvery && repeatedWallClock(t):
+			next = t.Add(time.Minute)
+		default:
+			return t
+		}
+		// 零点落在夏令时跳过的时段时 time.Date 可能返回更早的时间，至少前进一分钟
+		if !next.After(t) {
+			next = t.Add(time.Minute)
+		}
+		t = next
+	}
+	return time.Time{}
+}
+
+// repeatedWallClock 判断 t 的墙上时间是否在夏令时回拨前已经出现过一次
+func repeatedWallClock(t time.Time) bool {
+	_, offset := t.Zone()
+	_, before := t.Add(-time.Hour).Zone()
+	if before <= offset {
+		return false
+	}
+	earlier := t.Add(-time.Duration(before-offset) * time.Second)
+	return earlier.Hour() == t.Hour() && earlier.Minute() == t.Minute()
+}
+
+// dayMatches 判断日期是否满足日、周字段
+func (s *Schedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
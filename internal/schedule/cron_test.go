@@ -0,0 +1,171 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+// 2026-10-18 是周日
+var testNow = time.Date(2026, 10, 18, 10, 0, 0, 0, time.FixedZone("CST", 8*3600))
+
+func at(year int, month time.Month, day, hour, minute int) time.Time {
+	return time.Date(year, month, day, hour, minute, 0, 0, testNow.Location())
+}
+
+func TestScheduleNext(t *testing.T) {
+	tests := []struct {
+		spec  string
+		after time.Time
+		want  time.Time
+	}{
+		// 0 和 7 都表示周日
+		{"0 9 * * 0", testNow, at(2026, 10, 25, 9, 0)},
+		{"0 9 * * 7", testNow, at(2026, 10, 25, 9, 0)},
+		{"0 11 * * 7", testNow, at(2026, 10, 18, 11, 0)},
+		{"@weekly", testNow, at(2026, 10, 25, 0, 0)},
+		{"0 9 * * 1-5", testNow, at(2026, 10, 19, 9, 0)},
+
+		// 日、周都不是 * 时满足其一即可，有一个是 * 时两者都要满足
+		{"0 9 25 * 1", testNow, at(2026, 10, 19, 9, 0)},
+		{"0 9 20 * 5", testNow, at(2026, 10, 20, 9, 0)},
+		{"0 9 20 * *", testNow, at(2026, 10, 20, 9, 0)},
+		{"0 9 * * 5", testNow, at(2026, 10, 23, 9, 0)},
+		{"0 9 13 * 5", at(2026, 11, 1, 0, 0), at(2026, 11, 6, 9, 0)},
+
+		// 步长
+		{"5/15 * * * *", testNow, at(2026, 10, 18, 10, 5)},
+		{"5/15 * * * *", at(2026, 10, 18, 10, 5), at(2026, 10, 18, 10, 20)},
+		{"5/15 * * * *", at(2026, 10, 18, 10, 50), at(2026, 10, 18, 11, 5)},
+		{"*/30 9-18 * * *", at(2026, 10, 18, 18, 30), at(2026, 10, 19, 9, 0)},
+		{"0 0-23/6 * * *", testNow, at(2026, 10, 18, 12, 0)},
+		{"0,30 8 * * *", testNow, at(2026, 10, 19, 8, 0)},
+
+		// 不含 after 本身，秒数被忽略
+		{"0 10 * * *", testNow, at(2026, 10, 19, 10, 0)},
+		{"1 10 * * *", testNow.Add(30 * time.Second), at(2026, 10, 18, 10, 1)},
+
+		// 跨年、闰年
+		{"0 0 1 1 *", testNow, at(2027, 1, 1, 0, 0)},
+		{"0 0 29 2 *", testNow, at(2028, 2, 29, 0, 0)},
+
+		// 永远不会到来的日期
+		{"0 0 30 2 *", testNow, time.Time{}},
+		{"0 0 31 4 *", testNow, time.Time{}},
+	}
+
+	for _, tt := range tests {
+		s, err := ParseSchedule(tt.spec, testNow.Location())
+		if err != nil {
+			t.Errorf("ParseSchedule(%q) 出错: %v", tt.spec, err)
+			continue
+		}
+		if got := s.Next(tt.after); !got.Equal(tt.want) {
+			t.Errorf("%q.Next(%v) = %v，期望 %v", tt.spec, tt.after, got, tt.want)
+		}
+	}
+}
+
+func TestScheduleNextDST(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("没有时区数据: %v", err)
+	}
+	edt := time.FixedZone("EDT", -4*3600)
+	est := time.FixedZone("EST", -5*3600)
+
+	// 2026-03-08 02:00 EST 跳到 03:00 EDT；2026-11-01 02:00 EDT 回拨到 01:00 EST
+	tests := []struct {
+		name  string
+		spec  string
+		after time.Time
+		want  []time.Time
+	}{
+		{
+			name:  "夏令时开始时跳过不存在的时间",
+			spec:  "30 2 * * *",
+			after: time.Date(2026, 3, 7, 12, 0, 0, 0, ny),
+			want:  []time.Time{time.Date(2026, 3, 9, 2, 30, 0, 0, edt)},
+		},
+		{
+			name:  "夏令时开始当天跳变后的时间照常执行",
+			spec:  "0 3 * * *",
+			after: time.Date(2026, 3, 7, 12, 0, 0, 0, ny),
+			want: []time.Time{
+				time.Date(2026, 3, 8, 3, 0, 0, 0, edt),
+				time.Date(2026, 3, 9, 3, 0, 0, 0, edt),
+			},
+		},
+		{
+			name:  "夏令时开始时每小时任务少一次",
+			spec:  "0 * * * *",
+			after: time.Date(2026, 3, 8, 0, 30, 0, 0, ny),
+			want: []time.Time{
+				time.Date(2026, 3, 8, 1, 0, 0, 0, est),
+				time.Date(2026, 3, 8, 3, 0, 0, 0, edt),
+			},
+		},
+		{
+			name:  "回拨时固定时间只执行一次",
+			spec:  "30 1 * * *",
+			after: time.Date(2026, 10, 31, 12, 0, 0, 0, ny),
+			want: []time.Time{
+				time.Date(2026, 11, 1, 1, 30, 0, 0, edt),
+				time.Date(2026, 11, 2, 1, 30, 0, 0, est),
+			},
+		},
+		{
+			name:  "回拨时每半小时任务按实际时间继续",
+			spec:  "*/30 * * * *",
+			after: time.Date(2026, 11, 1, 1, 10, 0, 0, edt),
+			want: []time.Time{
+				time.Date(2026, 11, 1, 1, 30, 0, 0, edt),
+				time.Date(2026, 11, 1, 1, 0, 0, 0, est),
+				time.Date(2026, 11, 1, 1, 30, 0, 0, est),
+				time.Date(2026, 11, 1, 2, 0, 0, 0, est),
+			},
+		},
+		{
+			name:  "TZ 前缀覆盖默认时区",
+			spec:  "TZ=America/New_York 0 9 * * *",
+			after: testNow,
+			want:  []time.Time{time.Date(2026, 10, 18, 9, 0, 0, 0, edt)},
+		},
+	}
+
+	for _, tt := range tests {
+		s, err := ParseSchedule(tt.spec, ny)
+		if err != nil {
+			t.Errorf("%s: ParseSchedule(%q) 出错: %v", tt.name, tt.spec, err)
+			continue
+		}
+		after := tt.after
+		for i, want := range tt.want {
+			got := s.Next(after)
+			if !got.Equal(want) {
+				t.Errorf("%s: 第 %d 次 = %v，期望 %v", tt.name, i+1, got, want)
+				break
+			}
+			after = got
+		}
+	}
+}
+
+func TestParseScheduleErrors(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"0 9 * *",
+		"60 * * * *",
+		"0 24 * * *",
+		"0 0 0 * *",
+		"0 0 * 13 *",
+		"0 0 * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"TZ=Nowhere/City 0 9 * * *",
+	} {
+		if _, err := ParseSchedule(spec, testNow.Location()); err == nil {
+			t.Errorf("ParseSchedule(%q) 应当出错", spec)
+		}
+	}
+}
@@ -0,0 +1,280 @@
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"QQBot/internal/common"
+	"QQBot/internal/deepseek"
+	"QQBot/internal/handler"
+	"QQBot/internal/storage"
+)
+
+const (
+	scheduleDataName = "schedule"       // 定时任务的存储名称
+	scheduleTick     = 15 * time.Second // 检查到期任务的周期
+	scheduleGrace    = 2 * time.Minute  // 超过应执行时间此时长仍未执行，视为离线期间错过
+	catchUpMaxSteps  = 100000           // 统计错过次数时最多向后推算的次数
+
+	summaryHours = 24 // summary 模式总结最近多少小时的群聊
+
+	aiHint = "这是一条定时发送的消息，请直接输出要发送的内容，不要解释，不要加引号"
+)
+
+// 发送内容的模式
+const (
+	ModeText    = "text"    // 固定文本
+	ModeAI      = "ai"      // 按内容中的要求由 AI 生成
+	ModeSummary = "summary" // 总结群里最近一天的聊天（仅群聊）
+)
+
+// Job 定时任务
+type Job struct {
+	ID         int       `json:"id"`
+	Spec       string    `json:"spec"`        // cron 表达式
+	TargetType string    `json:"target_type"` // "group" 或 "private"
+	TargetID   int64     `json:"target_id"`   // 群号或 QQ 号
+	Mode       string    `json:"mode"`
+	Content    string    `json:"content"` // 固定文本，或交给 AI 的要求
+	Creator    int64     `json:"creator"`
+	Created    time.Time `json:"created"`
+	LastRun    time.Time `json:"last_run"` // 最近一次执行（或跳过）的时间
+
+	schedule *Schedule
+}
+
+// scheduleState 持久化的定时任务列表
+type scheduleState struct {
+	NextID int    `json:"next_id"`
+	Jobs   []*Job `json:"jobs"`
+}
+
+var (
+	scheduleMu sync.Mutex
+	state      *scheduleState
+	startOnce  sync.Once
+	loop       *common.TickLoop
+)
+
+// Start 启动定时任务检查（程序启动时调用一次），离线期间错过的任务按 ScheduleCatchUp 策略处理
+func Start() {
+	startOnce.Do(func() {
+		scheduleMu.Lock()
+		loadStateLocked()
+		log.Printf("[定时任务] 已加载 %d 个任务，时区 %s", len(state.Jobs), common.Location)
+		scheduleMu.Unlock()
+
+		loop = common.StartTickLoop(scheduleTick, true, runDue)
+	})
+}
+
+// Stop 停止定时任务检查并等待正在执行的一轮结束（程序退出前调用）
+func Stop() {
+	loop.Stop()
+}
+
+// AddJob 添加任务，返回带 ID 的任务副本
+func AddJob(job Job) (Job, error) {
+	sched, err := ParseSchedule(job.Spec, common.Location)
+	if err != nil {
+		return Job{}, err
+	}
+
+	scheduleMu.Lock()
+	defer scheduleMu.Unlock()
+	loadStateLocked()
+
+	state.NextID++
+	job.ID = state.NextID
+	job.Created = time.Now()
+	job.schedule = sched
+	state.Jobs = append(state.Jobs, &job)
+	saveStateLocked()
+
+	log.Printf("[定时任务] 添加 #%d: %s -> %s%d %s", job.ID, job.Spec, job.TargetType, job.TargetID, job.Mode)
+	return job, nil
+}
+
+// RemoveJob 删除任务，返回是否存在
+func RemoveJob(id int) bool {
+	scheduleMu.Lock()
+	defer scheduleMu.Unlock()
+	loadStateLocked()
+
+	for i, job := range state.Jobs {
+		if job.ID == id {
+			state.Jobs = append(state.Jobs[:i], state.Jobs[i+1:]...)
+			saveStateLocked()
+			log.Printf("[定时任务] 删除 #%d", id)
+			return true
+		}
+	}
+	return false
+}
+
+// Jobs 按 ID 顺序返回所有任务的副本
+func Jobs() []Job {
+	scheduleMu.Lock()
+	defer scheduleMu.Unlock()
+	loadStateLocked()
+
+	list := make([]Job, 0, len(state.Jobs))
+	for _, job := range state.Jobs {
+		list = append(list, *job)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
+
+// RunNow 立即执行一次任务（不影响正常的执行计划）
+func RunNow(id int) bool {
+	for _, job := range Jobs() {
+		if job.ID == id {
+			fire(job)
+			return true
+		}
+	}
+	return false
+}
+
+// NextRun 任务的下一次执行时间
+func (j Job) NextRun() time.Time {
+	if j.schedule == nil {
+		return time.Time{}
+	}
+	base := j.LastRun
+	if base.IsZero() || base.Before(j.Created) {
+		base = j.Created
+	}
+	next := j.schedule.Next(base)
+	if now := time.Now(); !next.IsZero() && next.Before(now) {
+		// 已错过（等待下一轮检查处理），显示之后的执行时间
+		next = j.schedule.Next(now)
+	}
+	return next
+}
+
+// runDue 执行所有到期的任务，未连接 NapCat 时等连接后再执行
+func runDue(now time.Time) {
+	if common.GetWebSocketConn() == nil {
+		return
+	}
+
+	scheduleMu.Lock()
+	loadStateLocked()
+
+	var due []Job
+	dirty := false
+	for _, job := range state.Jobs {
+		if job.schedule == nil {
+			continue
+		}
+		base := job.LastRun
+		if base.IsZero() || base.Before(job.Created) {
+			base = job.Created
+		}
+		next := job.schedule.Next(base)
+		if next.IsZero() || next.After(now) {
+			continue
+		}
+
+		job.LastRun = now
+		dirty = true
+
+		if now.Sub(next) <= scheduleGrace {
+			due = append(due, *job)
+			continue
+		}
+
+		// 离线期间错过：找到最近一次应执行的时间，按策略决定是否补执行一次
+		latest, missed := next, 1
+		for n := job.schedule.Next(latest); !n.IsZero() && !n.After(now) && missed < catchUpMaxSteps; n = job.schedule.Next(n) {
+			latest = n
+			missed++
+		}
+		if common.ScheduleCatchUp == "once" && now.Sub(latest) <= common.ScheduleCatchUpWindow {
+			log.Printf("[定时任务] #%d 离线期间错过 %d 次，补执行一次", job.ID, missed)
+			due = append(due, *job)
+		} else {
+			log.Printf("[定时任务] #%d 离线期间错过 %d 次，已跳过", job.ID, missed)
+		}
+	}
+	if dirty {
+		saveStateLocked()
+	}
+	scheduleMu.Unlock()
+
+	for _, job := range due {
+		fire(job)
+	}
+}
+
+// fire 执行任务：固定文本直接发送，AI 生成的内容提交到任务队列
+func fire(job Job) {
+	event := common.QQEvent{MsgType: job.TargetType}
+	if job.TargetType == "group" {
+		event.GroupID = job.TargetID
+	} else {
+		event.UserID = job.TargetID
+	}
+
+	if job.Mode == ModeText {
+		send(event, job.Content)
+		return
+	}
+
+	handler.Submit(context.Background(), "schedule", event, fmt.Sprintf("schedule:%d", job.ID), "", func(ctx context.Context) {
+		var text string
+		var err error
+		if job.Mode == ModeSummary {
+			text, err = deepseek.SummarizeGroup(ctx, job.TargetID, time.Now().Add(-summaryHours*time.Hour))
+			if err == nil && text == "" {
+				log.Printf("[定时任务] #%d 群%d 最近没什么消息，不发送总结", job.ID, job.TargetID)
+				return
+			}
+		} else {
+			text, err = deepseek.CallDeepSeekSimple(ctx, job.Content, aiHint)
+		}
+		if err != nil {
+			log.Printf("[定时任务] #%d 生成内容失败: %v", job.ID, err)
+			return
+		}
+		send(event, text)
+	})
+}
+
+// send 发送消息，群聊中同时加入上下文，便于群友接着聊
+func send(event common.QQEvent, text string) {
+	common.SendReply(event, text)
+	if event.MsgType == "group" {
+		storage.AddGroupContextMessage(event.GroupID, common.BotQQNumber, text)
+	}
+}
+
+// loadStateLocked 首次使用时从存储加载，并解析每个任务的表达式
+func loadStateLocked() {
+	if state != nil {
+		return
+	}
+	state = &scheduleState{}
+	storage.LoadData(scheduleDataName, state)
+	for _, job := range state.Jobs {
+		sched, err := ParseSchedule(job.Spec, common.Location)
+		if err != nil {
+			log.Printf("[定时任务] #%d 表达式无效，已停用: %v", job.ID, err)
+			continue
+		}
+		job.schedule = sched
+	}
+}
+
+// saveStateLocked 保存到存储
+func saveStateLocked() {
+	if err := storage.SaveData(scheduleDataName, state); err != nil {
+		log.Printf("[定时任务] 保存失败: %v", err)
+	}
+}
@@ -4,8 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
-	"os"
-	"path/filepath"
+	"strconv"
 	"sync"
 	"time"
 )
@@ -23,6 +22,8 @@ type Conversation struct {
 	UserID   int64     `json:"user_id"`
 	Messages []Message `json:"messages"`
 	mu       sync.RWMutex
+	evicted  bool // 已被清除，不再写回文件
+	saver    debouncedSaver
 }
 
 var (
@@ -83,25 +84,73 @@ func (c *Conversation) AddAssistantMessage(content string) {
 	// 限制历史长度
 	c.limitHistory()
 
-	// 保存到文件
-	go c.saveToFile()
+	// 延迟保存到文件
+	c.saver.markDirty(c.saveToFile)
 }
 
-// GetMessages 获取所有消息（用于 API 调用）
+// GetMessages 获取所有消息（用于 API 调用），用户消息中的标签括号会被转义
 func (c *Conversation) GetMessages() []map[string]string {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
 	messages := make([]map[string]string, 0, len(c.Messages))
 	for _, msg := range c.Messages {
+		content := msg.Content
+		if msg.Role == "user" {
+			content = SanitizeUserText(content)
+		}
 		messages = append(messages, map[string]string{
 			"role":    msg.Role,
-			"content": msg.Content,
+			"content": content,
 		})
 	}
 	return messages
 }
 
+// RecentMessages 获取最近 n 条消息的副本
+func (c *Conversation) RecentMessages(n int) []Message {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return recentTail(c.Messages, n)
+}
+
+// ClearConversation 清除用户的私聊历史（内存和存储），返回清除的消息数
+// 正在进行中的 AI 调用持有的旧对象会被标记为已清除，不会再写回文件
+func ClearConversation(userID int64) int {
+	count := 0
+	if convInterface, ok := privateConversations.LoadAndDelete(userID); ok {
+		conv := convInterface.(*Conversation)
+		conv.mu.Lock()
+		count = len(conv.Messages)
+		conv.Messages = nil
+		conv.evicted = true
+		conv.mu.Unlock()
+	} else if conv := loadConversationFromFile(userID); conv != nil {
+		count = len(conv.Messages)
+	}
+
+	if err := getStore().Delete(KindConversation, strconv.FormatInt(userID, 10)); err != nil {
+		log.Printf("[对话历史] 删除失败: %v", err)
+	}
+	log.Printf("[对话历史] 已清除用户%d的私聊历史，共 %d 条", userID, count)
+	return count
+}
+
+// ExportConversation 导出用户的私聊历史到 data/exports，返回文件路径和消息数
+func ExportConversation(userID int64) (string, int, error) {
+	conv := GetOrCreateConversation(userID)
+	conv.mu.RLock()
+	data, err := json.MarshalIndent(conv, "", "  ")
+	count := len(conv.Messages)
+	conv.mu.RUnlock()
+	if err != nil {
+		return "", 0, err
+	}
+
+	path, err := writeExportFile(fmt.Sprintf("user_%d", userID), data)
+	return path, count, err
+}
+
 // limitHistory 限制历史消息数量
 func (c *Conversation) limitHistory() {
 	if len(c.Messages) > MaxHistoryMessages {
@@ -110,43 +159,25 @@ func (c *Conversation) limitHistory() {
 	}
 }
 
-// saveToFile 保存对话历史到文件
+// saveToFile 保存对话历史
 func (c *Conversation) saveToFile() {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
-	// 确保目录存在
-	if err := os.MkdirAll(HistoryDataDir, 0755); err != nil {
-		log.Printf("[对话历史] 创建目录失败: %v", err)
-		return
-	}
-
-	// 保存到文件
-	filename := filepath.Join(HistoryDataDir, fmt.Sprintf("user_%d.json", c.UserID))
-	data, err := json.MarshalIndent(c, "", "  ")
-	if err != nil {
-		log.Printf("[对话历史] 序列化失败: %v", err)
+	if c.evicted {
 		return
 	}
 
-	if err := os.WriteFile(filename, data, 0644); err != nil {
-		log.Printf("[对话历史] 保存文件失败: %v", err)
+	if err := saveJSON(KindConversation, strconv.FormatInt(c.UserID, 10), c); err != nil {
+		log.Printf("[对话历史] 保存失败: %v", err)
 	}
 }
 
-// loadConversationFromFile 从文件加载对话历史
+// loadConversationFromFile 加载对话历史
 func loadConversationFromFile(userID int64) *Conversation {
-	filename := filepath.Join(HistoryDataDir, fmt.Sprintf("user_%d.json", userID))
-
-	data, err := os.ReadFile(filename)
-	if err != nil {
-		// 文件不存在是正常的，返回 nil
-		return nil
-	}
-
 	var conv Conversation
-	if err := json.Unmarshal(data, &conv); err != nil {
-		log.Printf("[对话历史] 加载文件失败: %v", err)
+	if !loadJSON(KindConversation, strconv.FormatInt(userID, 10), &conv) {
+		// 不存在是正常的，返回 nil
 		return nil
 	}
 
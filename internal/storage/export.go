@@ -0,0 +1,29 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// recentTail 返回切片最后 n 条的副本（n<=0 时返回全部）
+func recentTail[T any](items []T, n int) []T {
+	if n <= 0 || n > len(items) {
+		n = len(items)
+	}
+	return append([]T(nil), items[len(items)-n:]...)
+}
+
+// writeExportFile 将导出数据写入 ExportDataDir，文件名带时间戳，返回绝对路径
+func writeExportFile(name string, data []byte) (string, error) {
+	if err := os.MkdirAll(ExportDataDir, 0755); err != nil {
+		return "", err
+	}
+
+	filename := filepath.Join(ExportDataDir, fmt.Sprintf("%s_%s.json", name, time.Now().Format("20060102-150405")))
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		return "", err
+	}
+	return filepath.Abs(filename)
+}
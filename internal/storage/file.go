@@ -0,0 +1,101 @@
+package storage
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// writeFileAtomic 原子写入文件：先写临时文件并 fsync，再重命名替换
+// 替换前旧文件会保留为 .bak，主文件损坏时可从备份恢复
+func writeFileAtomic(filename string, data []byte) error {
+	dir := filepath.Dir(filename)
+	tmp, err := os.CreateTemp(dir, filepath.Base(filename)+".tmp*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName) // 重命名成功后临时文件已不存在，删除失败可忽略
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpName, 0644); err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(filename); err == nil {
+		if err := os.Rename(filename, filename+".bak"); err != nil {
+			log.Printf("[存储] 备份旧文件失败: %v", err)
+		}
+	}
+	if err := os.Rename(tmpName, filename); err != nil {
+		return err
+	}
+
+	// 同步目录，确保重命名持久化（部分平台不支持，忽略错误）
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
+
+// removeWithBackup 删除文件及其备份
+func removeWithBackup(filename string) error {
+	if err := os.Remove(filename + ".bak"); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Remove(filename); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// debouncedSaver 合并短时间内的多次保存：标记为脏后延迟 SaveDebounceInterval 再保存
+type debouncedSaver struct {
+	mu     sync.Mutex
+	dirty  bool
+	timer  *time.Timer
+	saveMu sync.Mutex // 保证同一对象的保存串行执行
+}
+
+// markDirty 标记需要保存，尚未安排保存时安排一次延迟保存
+func (d *debouncedSaver) markDirty(save func()) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.dirty = true
+	if d.timer == nil {
+		d.timer = time.AfterFunc(SaveDebounceInterval, func() { d.flush(save) })
+	}
+}
+
+// flush 如有未保存的修改则立即保存
+func (d *debouncedSaver) flush(save func()) bool {
+	d.saveMu.Lock()
+	defer d.saveMu.Unlock()
+
+	d.mu.Lock()
+	if d.timer != nil {
+		d.timer.Stop()
+		d.timer = nil
+	}
+	dirty := d.dirty
+	d.dirty = false
+	d.mu.Unlock()
+
+	if dirty {
+		save()
+	}
+	return dirty
+}
@@ -0,0 +1,33 @@
+package storage
+
+import (
+	"log"
+)
+
+// FlushAll 立即保存内存中所有有未保存修改的对话历史、群聊上下文和昵称映射
+// 用于程序退出前，确保数据完整写入
+func FlushAll() {
+	conversations, contexts, nicknames := 0, 0, 0
+	privateConversations.Range(func(_, value interface{}) bool {
+		c := value.(*Conversation)
+		if c.saver.flush(c.saveToFile) {
+			conversations++
+		}
+		return true
+	})
+	groupContexts.Range(func(_, value interface{}) bool {
+		c := value.(*GroupContext)
+		if c.saver.flush(c.saveToFile) {
+			contexts++
+		}
+		return true
+	})
+	groupNicknameMap.Range(func(_, value interface{}) bool {
+		nm := value.(*GroupNicknameMap)
+		if nm.saver.flush(nm.saveToFile) {
+			nicknames++
+		}
+		return true
+	})
+	log.Printf("[存储] 已保存 %d 个私聊历史、%d 个群聊上下文、%d 个昵称映射", conversations, contexts, nicknames)
+}
@@ -4,8 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
-	"os"
-	"path/filepath"
+	"strconv"
 	"sync"
 	"time"
 )
@@ -22,6 +21,8 @@ type GroupContext struct {
 	GroupID  int64                 `json:"group_id"`
 	Messages []GroupContextMessage `json:"messages"`
 	mu       sync.RWMutex
+	evicted  bool // 已被清除，不再写回文件
+	saver    debouncedSaver
 }
 
 var (
@@ -59,8 +60,8 @@ func AddGroupContextMessage(groupID int64, userID int64, content string) {
 		ctx.Messages = ctx.Messages[len(ctx.Messages)-MaxGroupContextMessages:]
 	}
 
-	// 保存到文件
-	go ctx.saveToFile()
+	// 延迟保存到文件
+	ctx.saver.markDirty(ctx.saveToFile)
 	// log.Printf("[DEBUG] [群聊上下文] 群%d: 消息数 %d", groupID, len(ctx.Messages))
 }
 
@@ -94,6 +95,50 @@ func GetGroupContextForAI(groupID int64) (context string, lastMessage *GroupCont
 	return contextMsg, lastMsg
 }
 
+// RecentGroupContextMessages 获取群聊上下文最近 n 条消息的副本
+func RecentGroupContextMessages(groupID int64, n int) []GroupContextMessage {
+	ctx := getOrCreateGroupContext(groupID)
+	ctx.mu.RLock()
+	defer ctx.mu.RUnlock()
+	return recentTail(ctx.Messages, n)
+}
+
+// ClearGroupContext 清除群聊上下文（内存和存储），返回清除的消息数
+func ClearGroupContext(groupID int64) int {
+	count := 0
+	if ctxInterface, ok := groupContexts.LoadAndDelete(groupID); ok {
+		ctx := ctxInterface.(*GroupContext)
+		ctx.mu.Lock()
+		count = len(ctx.Messages)
+		ctx.Messages = nil
+		ctx.evicted = true
+		ctx.mu.Unlock()
+	} else if ctx := loadGroupContextFromFile(groupID); ctx != nil {
+		count = len(ctx.Messages)
+	}
+
+	if err := getStore().Delete(KindGroupContext, strconv.FormatInt(groupID, 10)); err != nil {
+		log.Printf("[群聊上下文] 删除失败: %v", err)
+	}
+	log.Printf("[群聊上下文] 已清除群%d的上下文，共 %d 条", groupID, count)
+	return count
+}
+
+// ExportGroupContext 导出群聊上下文到 data/exports，返回文件路径和消息数
+func ExportGroupContext(groupID int64) (string, int, error) {
+	ctx := getOrCreateGroupContext(groupID)
+	ctx.mu.RLock()
+	data, err := json.MarshalIndent(ctx, "", "  ")
+	count := len(ctx.Messages)
+	ctx.mu.RUnlock()
+	if err != nil {
+		return "", 0, err
+	}
+
+	path, err := writeExportFile(fmt.Sprintf("group_%d", groupID), data)
+	return path, count, err
+}
+
 // getOrCreateGroupContext 获取或创建群聊上下文（从文件加载）
 func getOrCreateGroupContext(groupID int64) *GroupContext {
 	// 先从内存中查找
@@ -119,43 +164,26 @@ func getOrCreateGroupContext(groupID int64) *GroupContext {
 	return ctx
 }
 
-// saveToFile 保存群聊上下文到文件
+// saveToFile 保存群聊上下文
 func (c *GroupContext) saveToFile() {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
-	// 确保目录存在
-	if err := os.MkdirAll(HistoryDataDir, 0755); err != nil {
-		log.Printf("[群聊上下文] 创建目录失败: %v", err)
+	if c.evicted {
 		return
 	}
 
-	filename := filepath.Join(HistoryDataDir, fmt.Sprintf("group_%d.json", c.GroupID))
-	data, err := json.MarshalIndent(c, "", "  ")
-	if err != nil {
-		log.Printf("[群聊上下文] 序列化失败: %v", err)
-		return
+	if err := saveJSON(KindGroupContext, strconv.FormatInt(c.GroupID, 10), c); err != nil {
+		log.Printf("[群聊上下文] 保存失败: %v", err)
 	}
-
-	if err := os.WriteFile(filename, data, 0644); err != nil {
-		log.Printf("[群聊上下文] 保存文件失败: %v", err)
-	}
-	// log.Printf("[DEBUG] [群聊上下文] 群%d: 已保存到文件", c.GroupID)
+	// log.Printf("[DEBUG] [群聊上下文] 群%d: 已保存", c.GroupID)
 }
 
-// loadGroupContextFromFile 从文件加载群聊上下文
+// loadGroupContextFromFile 加载群聊上下文
 func loadGroupContextFromFile(groupID int64) *GroupContext {
-	filename := filepath.Join(HistoryDataDir, fmt.Sprintf("group_%d.json", groupID))
-
-	data, err := os.ReadFile(filename)
-	if err != nil {
-		// 文件不存在是正常的
-		return nil
-	}
-
 	var ctx GroupContext
-	if err := json.Unmarshal(data, &ctx); err != nil {
-		log.Printf("[群聊上下文] 加载文件失败: %v", err)
+	if !loadJSON(KindGroupContext, strconv.FormatInt(groupID, 10), &ctx) {
+		// 不存在是正常的
 		return nil
 	}
 
@@ -2,11 +2,8 @@ package storage
 
 import (
 	"QQBot/internal/common"
-	"encoding/json"
-	"fmt"
 	"log"
-	"os"
-	"path/filepath"
+	"strconv"
 	"sync"
 )
 
@@ -15,6 +12,7 @@ type GroupNicknameMap struct {
 	GroupID   int64            `json:"group_id"`
 	Nicknames map[int64]string `json:"nicknames"` // QQ号 -> 昵称
 	mu        sync.RWMutex
+	saver     debouncedSaver
 }
 
 var (
@@ -39,8 +37,8 @@ func UpdateNicknameMap(groupID int64, userID int64, nickname string) {
 
 	nm.Nicknames[userID] = nickname
 
-	// 异步保存到文件
-	go nm.saveToFile()
+	// 延迟保存到文件
+	nm.saver.markDirty(nm.saveToFile)
 }
 
 // GetNickname 获取用户昵称，如果不存在则返回稳定标识符
@@ -88,42 +86,21 @@ func getOrCreateGroupNicknameMap(groupID int64) *GroupNicknameMap {
 	return nm
 }
 
-// saveToFile 保存群昵称映射到文件
+// saveToFile 保存群昵称映射
 func (nm *GroupNicknameMap) saveToFile() {
 	nm.mu.RLock()
 	defer nm.mu.RUnlock()
 
-	// 确保目录存在
-	if err := os.MkdirAll(HistoryDataDir, 0755); err != nil {
-		log.Printf("[昵称映射] 创建目录失败: %v", err)
-		return
-	}
-
-	filename := filepath.Join(HistoryDataDir, fmt.Sprintf("group_%d_nicknames.json", nm.GroupID))
-	data, err := json.MarshalIndent(nm, "", "  ")
-	if err != nil {
-		log.Printf("[昵称映射] 序列化失败: %v", err)
-		return
-	}
-
-	if err := os.WriteFile(filename, data, 0644); err != nil {
-		log.Printf("[昵称映射] 保存文件失败: %v", err)
+	if err := saveJSON(KindNicknames, strconv.FormatInt(nm.GroupID, 10), nm); err != nil {
+		log.Printf("[昵称映射] 保存失败: %v", err)
 	}
 }
 
-// loadGroupNicknameMapFromFile 从文件加载群昵称映射
+// loadGroupNicknameMapFromFile 加载群昵称映射
 func loadGroupNicknameMapFromFile(groupID int64) *GroupNicknameMap {
-	filename := filepath.Join(HistoryDataDir, fmt.Sprintf("group_%d_nicknames.json", groupID))
-
-	data, err := os.ReadFile(filename)
-	if err != nil {
-		// 文件不存在是正常的
-		return nil
-	}
-
 	var nm GroupNicknameMap
-	if err := json.Unmarshal(data, &nm); err != nil {
-		log.Printf("[昵称映射] 加载文件失败: %v", err)
+	if !loadJSON(KindNicknames, strconv.FormatInt(groupID, 10), &nm) {
+		// 不存在是正常的
 		return nil
 	}
 
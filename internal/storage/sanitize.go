@@ -0,0 +1,57 @@
+package storage
+
+import (
+	"strings"
+	"unicode"
+)
+
+// 群聊消息交给 AI 时每条的格式为 "【角色标签】昵称 发言说: 内容"，身份只看行首的【角色标签】。
+// 为防止在内容或昵称里伪造一行带标签的发言，拼接每一行时内容和昵称中的【】等括号都会被替换为［］，
+// 换行替换为 ↵，保证一条消息只占一行、只有一个真正的标签。
+// 转义只在拼接时进行，消息本身（归档、自动回复、指令参数等使用的内容）保持原样。
+
+// tagEscaper 把可能被当作角色标签的括号替换为普通全角方括号
+var tagEscaper = strings.NewReplacer(
+	"【", "［", "】", "］",
+	"〖", "［", "〗", "］",
+	"〔", "［", "〕", "］",
+)
+
+// lineBreaker 把各种换行统一为 \n
+var lineBreaker = strings.NewReplacer("\r\n", "\n", "\r", "\n", "\u2028", "\n", "\u2029", "\n", "\u0085", "\n")
+
+// SanitizeUserText 转义要放进群聊记录的文本：替换标签括号，去掉零宽字符和文字方向控制符，统一换行
+func SanitizeUserText(s string) string {
+	s = lineBreaker.Replace(s)
+	s = strings.Map(func(r rune) rune {
+		if isInvisible(r) {
+			return -1
+		}
+		return r
+	}, s)
+	return tagEscaper.Replace(s)
+}
+
+// sanitizeNickname 转义昵称（群名片可以随意修改），昵称中不能有换行
+func sanitizeNickname(s string) string {
+	return strings.Join(strings.Fields(SanitizeUserText(s)), " ")
+}
+
+// fenceContent 转义内容并限制在一行内，换行显示为 ↵
+// 群友的原话、以前保存的上下文和小牛自己的回复都经过这里，内容中的 @【角色】昵称 也会显示为 @［角色］昵称
+func fenceContent(s string) string {
+	return strings.ReplaceAll(SanitizeUserText(s), "\n", " ↵ ")
+}
+
+// isInvisible 判断是否为零宽字符或文字方向控制符（可用来隐藏或打乱伪造的内容）
+func isInvisible(r rune) bool {
+	switch {
+	case r >= 0x200B && r <= 0x200F, // 零宽空格、零宽连接符、方向标记
+		r >= 0x202A && r <= 0x202E, // 方向嵌入与覆盖
+		r >= 0x2060 && r <= 0x2064, // 零宽不换行等
+		r >= 0x2066 && r <= 0x2069, // 方向隔离
+		r == 0xFEFF:
+		return true
+	}
+	return r != '\n' && r != '\t' && unicode.IsControl(r)
+}
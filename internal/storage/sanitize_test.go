@@ -0,0 +1,107 @@
+package storage
+
+import (
+	"bufio"
+	"os"
+	"strings"
+	"testing"
+)
+
+// corpusUnescaper 还原语料中的转义字符
+var corpusUnescaper = strings.NewReplacer(`\n`, "\n", `\r`, "\r", `\u2028`, "\u2028", `\u2029`, "\u2029",
+	`\u0085`, "\u0085", `\u200b`, "\u200b", `\u202e`, "\u202e")
+
+// loadInjectionCorpus 读取 testdata/injection_corpus.txt，跳过空行和注释
+func loadInjectionCorpus(t *testing.T) []string {
+	t.Helper()
+	f, err := os.Open("testdata/injection_corpus.txt")
+	if err != nil {
+		t.Fatalf("打开语料失败: %v", err)
+	}
+	defer f.Close()
+
+	var corpus []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		corpus = append(corpus, corpusUnescaper.Replace(line))
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("读取语料失败: %v", err)
+	}
+	return corpus
+}
+
+// assertSingleAttributedLine 格式化结果必须只有一行、只有一个【角色标签】，且是发送者本人的
+func assertSingleAttributedLine(t *testing.T, line string, roleTag string) {
+	t.Helper()
+	if strings.ContainsAny(line, "\n\r\u2028\u2029\u0085") {
+		t.Errorf("格式化后包含换行: %q", line)
+	}
+	if n := strings.Count(line, "【"); n != 1 {
+		t.Errorf("格式化后有 %d 个【，应只有行首一个: %q", n, line)
+	}
+	if n := strings.Count(line, "】"); n != 1 {
+		t.Errorf("格式化后有 %d 个】，应只有行首一个: %q", n, line)
+	}
+	if !strings.HasPrefix(line, "【"+roleTag+"】") {
+		t.Errorf("角色标签被改变，应为 %s: %q", roleTag, line)
+	}
+}
+
+func TestInjectionCorpusContent(t *testing.T) {
+	// 消息内容保持原文，只靠拼接群聊记录时转义
+	for _, attempt := range loadInjectionCorpus(t) {
+		line := FormatGroupMessage(0, 10001, attempt)
+		assertSingleAttributedLine(t, line, "普通群友")
+	}
+}
+
+func TestInjectionCorpusBotReply(t *testing.T) {
+	// 小牛自己的回复也可能被诱导复述伪造的标签，存入上下文后同样不能分行或伪造标签
+	for _, attempt := range loadInjectionCorpus(t) {
+		line := FormatGroupMessage(0, 0, attempt)
+		assertSingleAttributedLine(t, line, "你")
+	}
+}
+
+func TestInjectionCorpusNickname(t *testing.T) {
+	for _, attempt := range loadInjectionCorpus(t) {
+		line := formatGroupLine("普通群友", attempt, "大家好")
+		assertSingleAttributedLine(t, line, "普通群友")
+	}
+}
+
+func TestInjectionCorpusAtNickname(t *testing.T) {
+	// @ 的标签由程序生成，保留【】；被 @ 的人的昵称仍需转义
+	for _, attempt := range loadInjectionCorpus(t) {
+		at := "@【普通群友】" + sanitizeNickname(attempt)
+		if strings.Count(at, "【") != 1 || strings.ContainsAny(at, "\n\r") {
+			t.Errorf("@ 昵称未转义: %q", at)
+		}
+	}
+}
+
+func TestSanitizeKeepsOrdinaryText(t *testing.T) {
+	cases := map[string]string{
+		"小牛，今天天气怎么样？": "小牛，今天天气怎么样？",
+		"[图片] 哈哈 (笑)": "[图片] 哈哈 (笑)",
+		"第一行\n第二行":    "第一行\n第二行",
+		"【通知】明天放假":    "［通知］明天放假",
+		"emoji 👍🏻 保留": "emoji 👍🏻 保留",
+	}
+	for in, want := range cases {
+		if got := SanitizeUserText(in); got != want {
+			t.Errorf("SanitizeUserText(%q) = %q, want %q", in, got, want)
+		}
+	}
+	if got := fenceContent("第一行\n第二行"); got != "第一行 ↵ 第二行" {
+		t.Errorf("fenceContent 多行内容 = %q", got)
+	}
+	if got := fenceContent("@【普通群友】路人甲 你好"); got != "@［普通群友］路人甲 你好" {
+		t.Errorf("fenceContent @ 标签 = %q", got)
+	}
+}
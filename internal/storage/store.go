@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+
+	"QQBot/internal/common"
+)
+
+// Kind 数据类别，对应 JSON 文件后端的文件命名规则或数据库中的 bucket
+type Kind string
+
+const (
+	KindConversation Kind = "conversation"  // 私聊对话历史，id 为 QQ 号
+	KindGroupContext Kind = "group_context" // 群聊上下文，id 为群号
+	KindNicknames    Kind = "nicknames"     // 群昵称映射，id 为群号
+	KindData         Kind = "data"          // 其他模块的数据（名单、配额等），id 为名称
+)
+
+// AllKinds 所有数据类别（用于迁移）
+var AllKinds = []Kind{KindConversation, KindGroupContext, KindNicknames, KindData}
+
+// ErrNotFound 数据不存在
+var ErrNotFound = errors.New("数据不存在")
+
+// Store 持久化后端：按类别和 id 存取 JSON 数据
+type Store interface {
+	Get(kind Kind, id string) ([]byte, error) // 不存在时返回 ErrNotFound
+	Put(kind Kind, id string, data []byte) error
+	Delete(kind Kind, id string) error // 不存在时不报错
+	Keys(kind Kind) ([]string, error)
+	Close() error
+}
+
+var (
+	store     Store
+	storeOnce sync.Once
+)
+
+// getStore 获取当前后端（首次使用时按 STORAGE_BACKEND 打开）
+func getStore() Store {
+	storeOnce.Do(func() {
+		s, err := OpenStore(common.StorageBackend, HistoryDataDir, common.StorageDBPath)
+		if err != nil {
+			log.Fatalf("[存储] 打开 %s 存储失败: %v", common.StorageBackend, err)
+		}
+		store = s
+		log.Printf("[存储] 使用 %s 存储", common.StorageBackend)
+	})
+	return store
+}
+
+// OpenStore 打开指定后端："json"（每个对象一个文件，位于 dir）或 "bolt"（嵌入式数据库，位于 dbPath）
+func OpenStore(backend string, dir string, dbPath string) (Store, error) {
+	switch backend {
+	case "json":
+		return newJSONStore(dir), nil
+	case "bolt":
+		return openBoltStore(dbPath)
+	}
+	return nil, errors.New("未知的存储后端: " + backend)
+}
+
+// Close 关闭后端（程序退出前调用，需在 FlushAll 之后）
+func Close() {
+	if store == nil {
+		return
+	}
+	if err := store.Close(); err != nil {
+		log.Printf("[存储] 关闭失败: %v", err)
+	}
+}
+
+// LoadData 读取其他模块的数据到 v，返回是否存在
+func LoadData(name string, v interface{}) bool {
+	return loadJSON(KindData, name, v)
+}
+
+// SaveData 保存其他模块的数据
+func SaveData(name string, v interface{}) error {
+	return saveJSON(KindData, name, v)
+}
+
+// loadJSON 读取并解析数据，不存在或解析失败时返回 false
+func loadJSON(kind Kind, id string, v interface{}) bool {
+	data, err := getStore().Get(kind, id)
+	if err != nil {
+		if !errors.Is(err, ErrNotFound) {
+			log.Printf("[存储] 读取 %s/%s 失败: %v", kind, id, err)
+		}
+		return false
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		log.Printf("[存储] 解析 %s/%s 失败: %v", kind, id, err)
+		return false
+	}
+	return true
+}
+
+// saveJSON 序列化并保存数据
+func saveJSON(kind Kind, id string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return getStore().Put(kind, id, data)
+}
@@ -0,0 +1,121 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// jsonStore JSON 文件后端：沿用 data/ 目录下每个对象一个文件的布局
+//
+//	conversation  -> user_{QQ号}.json
+//	group_context -> group_{群号}.json
+//	nicknames     -> group_{群号}_nicknames.json
+//	data          -> {名称}.json
+type jsonStore struct {
+	dir string
+}
+
+// 文件名 -> id 的匹配规则（用于 Keys）
+var jsonKeyPatterns = map[Kind]*regexp.Regexp{
+	KindConversation: regexp.MustCompile(`^user_(\d+)\.json$`),
+	KindGroupContext: regexp.MustCompile(`^group_(\d+)\.json$`),
+	KindNicknames:    regexp.MustCompile(`^group_(\d+)_nicknames\.json$`),
+}
+
+func newJSONStore(dir string) *jsonStore {
+	return &jsonStore{dir: dir}
+}
+
+// path 获取数据对应的文件路径
+func (s *jsonStore) path(kind Kind, id string) string {
+	switch kind {
+	case KindConversation:
+		return filepath.Join(s.dir, fmt.Sprintf("user_%s.json", id))
+	case KindGroupContext:
+		return filepath.Join(s.dir, fmt.Sprintf("group_%s.json", id))
+	case KindNicknames:
+		return filepath.Join(s.dir, fmt.Sprintf("group_%s_nicknames.json", id))
+	}
+	return filepath.Join(s.dir, id+".json")
+}
+
+// Get 读取数据，主文件缺失或损坏时从 .bak 恢复
+func (s *jsonStore) Get(kind Kind, id string) ([]byte, error) {
+	filename := s.path(kind, id)
+	data, err := os.ReadFile(filename)
+	if err == nil && json.Valid(data) {
+		return data, nil
+	}
+	if err == nil {
+		log.Printf("[存储] %s 已损坏，尝试从备份恢复", filename)
+	}
+
+	backup, bakErr := os.ReadFile(filename + ".bak")
+	if bakErr != nil || !json.Valid(backup) {
+		if err != nil && os.IsNotExist(err) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("%s 无法解析且没有可用的备份", filename)
+	}
+	log.Printf("[存储] 已从备份恢复 %s", filename)
+	return backup, nil
+}
+
+// Put 原子写入数据
+func (s *jsonStore) Put(kind Kind, id string, data []byte) error {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return err
+	}
+	return writeFileAtomic(s.path(kind, id), data)
+}
+
+// Delete 删除数据及其备份
+func (s *jsonStore) Delete(kind Kind, id string) error {
+	return removeWithBackup(s.path(kind, id))
+}
+
+// Keys 列出某类数据的所有 id
+func (s *jsonStore) Keys(kind Kind) ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var keys []string
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		name := e.Name()
+		if pattern, ok := jsonKeyPatterns[kind]; ok {
+			if m := pattern.FindStringSubmatch(name); m != nil {
+				keys = append(keys, m[1])
+			}
+			continue
+		}
+		// data 类：不属于其他类别的 .json 文件
+		if strings.HasSuffix(name, ".json") && !s.matchesOtherKind(name) {
+			keys = append(keys, strings.TrimSuffix(name, ".json"))
+		}
+	}
+	return keys, nil
+}
+
+func (s *jsonStore) matchesOtherKind(name string) bool {
+	for _, pattern := range jsonKeyPatterns {
+		if pattern.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *jsonStore) Close() error { return nil }
@@ -22,15 +22,24 @@ func GetRoleTag(userID int64) string {
 // FormatAtMessage 格式化 @ 消息：@【角色标签】昵称
 func FormatAtMessage(groupID int64, userID int64) string {
 	roleTag := GetRoleTag(userID)
-	nickname := GetNickname(groupID, userID)
+	nickname := sanitizeNickname(GetNickname(groupID, userID))
 	return fmt.Sprintf("@【%s】%s", roleTag, nickname)
 }
 
 // FormatGroupMessage 格式化群聊消息：【角色标签】昵称 发言说: 内容
+// 昵称和内容在这里转义，无法伪造角色标签或另起一行（见 sanitize.go）
 func FormatGroupMessage(groupID int64, userID int64, content string) string {
-	roleTag := GetRoleTag(userID)
-	nickname := GetNickname(groupID, userID)
-	return fmt.Sprintf("【%s】%s 发言说: %s", roleTag, nickname, content)
+	return formatGroupLine(GetRoleTag(userID), GetNickname(groupID, userID), content)
+}
+
+// FormatGroupMessageAs 按给定的昵称格式化群聊消息（如保存下来的 @ 记录，发送者之后可能改了群名片）
+func FormatGroupMessageAs(userID int64, nickname string, content string) string {
+	return formatGroupLine(GetRoleTag(userID), nickname, content)
+}
+
+// formatGroupLine 按角色标签、昵称和内容拼出一行群聊消息
+func formatGroupLine(roleTag string, nickname string, content string) string {
+	return fmt.Sprintf("【%s】%s 发言说: %s", roleTag, sanitizeNickname(nickname), fenceContent(content))
 }
 
 // getUserStableID 获取用户的稳定标识符（基于QQ号的哈希，如"用户AA"）
@@ -0,0 +1,235 @@
+package usage
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"QQBot/internal/common"
+	"QQBot/internal/storage"
+)
+
+const (
+	dateLayout       = "2006-01-02"  // 明细文件按天切分，文件名为日期
+	usageDataName    = "usage_daily" // 每日汇总的存储名称
+	legacyTotalsFile = "daily.json"  // 旧版本写在 UsageDataDir 下的汇总文件，首次加载时迁移
+)
+
+// UsageDataDir 用量明细存储目录：每天一个明细文件（YYYY-MM-DD.jsonl）
+var UsageDataDir = filepath.Join(storage.HistoryDataDir, "usage")
+
+// Record 单次 API 调用的用量
+type Record struct {
+	Time             string  `json:"time"`
+	UserID           int64   `json:"user_id"`
+	GroupID          int64   `json:"group_id"`
+	Model            string  `json:"model"`
+	LatencyMs        int64   `json:"latency_ms"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	CacheHitTokens   int     `json:"cache_hit_tokens"`
+	CacheMissTokens  int     `json:"cache_miss_tokens"`
+	Cost             float64 `json:"cost"` // 估算费用（元）
+}
+
+// Totals 用量汇总
+type Totals struct {
+	Requests         int     `json:"requests"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	CacheHitTokens   int     `json:"cache_hit_tokens"`
+	LatencyMs        int64   `json:"latency_ms"` // 累计耗时，用于计算平均值
+	Cost             float64 `json:"cost"`
+}
+
+// DailyTotals 某一天的用量汇总
+type DailyTotals struct {
+	Totals
+	Users map[int64]*Totals `json:"users"`
+}
+
+var (
+	usageMu       sync.Mutex
+	daily         map[string]*DailyTotals // 日期 -> 汇总
+	lastPruneDate string                  // 最近一次清理过期用量的日期，每天最多清理一次
+)
+
+// Add 记录一次 API 调用：计算费用、追加明细、更新每日汇总
+func Add(rec Record) {
+	now := time.Now()
+	if rec.Time == "" {
+		rec.Time = now.Format(time.RFC3339)
+	}
+	rec.Cost = EstimateCost(rec)
+	date := now.Format(dateLayout)
+
+	usageMu.Lock()
+	defer usageMu.Unlock()
+	loadTotalsLocked()
+	if lastPruneDate != date {
+		lastPruneDate = date
+		pruneLocked(now)
+	}
+
+	appendRecordLocked(date, rec)
+
+	day, ok := daily[date]
+	if !ok {
+		day = &DailyTotals{Users: make(map[int64]*Totals)}
+		daily[date] = day
+	}
+	day.add(rec)
+	if rec.UserID != 0 {
+		u, ok := day.Users[rec.UserID]
+		if !ok {
+			u = &Totals{}
+			day.Users[rec.UserID] = u
+		}
+		u.add(rec)
+	}
+	saveTotalsLocked()
+}
+
+// EstimateCost 按模型价格估算费用（元），未配置价格的模型返回 0
+func EstimateCost(rec Record) float64 {
+	price, ok := common.ModelPrices[rec.Model]
+	if !ok {
+		return 0
+	}
+	hit, miss := rec.CacheHitTokens, rec.CacheMissTokens
+	if hit+miss == 0 {
+		// 未返回缓存明细时按全部未命中计算
+		miss = rec.PromptTokens
+	}
+	return (float64(hit)*price.CacheHitInput + float64(miss)*price.CacheMissInput +
+		float64(rec.CompletionTokens)*price.Output) / 1e6
+}
+
+func (t *Totals) add(rec Record) {
+	t.Requests++
+	t.PromptTokens += rec.PromptTokens
+	t.CompletionTokens += rec.CompletionTokens
+	t.CacheHitTokens += rec.CacheHitTokens
+	t.LatencyMs += rec.LatencyMs
+	t.Cost += rec.Cost
+}
+
+// appendRecordLocked 追加明细到当天的 jsonl 文件
+func appendRecordLocked(date string, rec Record) {
+	if err := os.MkdirAll(UsageDataDir, 0755); err != nil {
+		log.Printf("[用量] 创建目录失败: %v", err)
+		return
+	}
+
+	line, err := json.Marshal(rec)
+	if err != nil {
+		log.Printf("[用量] 序列化失败: %v", err)
+		return
+	}
+
+	f, err := os.OpenFile(filepath.Join(UsageDataDir, fmt.Sprintf("%s.jsonl", date)), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		log.Printf("[用量] 打开明细文件失败: %v", err)
+		return
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		log.Printf("[用量] 写入明细失败: %v", err)
+	}
+}
+
+// loadTotalsLocked 首次使用时加载每日汇总
+func loadTotalsLocked() {
+	if daily != nil {
+		return
+	}
+	daily = make(map[string]*DailyTotals)
+
+	if !storage.LoadData(usageDataName, &daily) && !loadLegacyTotalsLocked() {
+		return
+	}
+	for _, day := range daily {
+		if day.Users == nil {
+			day.Users = make(map[int64]*Totals)
+		}
+	}
+}
+
+// loadLegacyTotalsLocked 读取旧版本的 data/usage/daily.json，迁移到存储后端后删除
+func loadLegacyTotalsLocked() bool {
+	legacy := filepath.Join(UsageDataDir, legacyTotalsFile)
+	data, err := os.ReadFile(legacy)
+	if err != nil {
+		return false
+	}
+	if err := json.Unmarshal(data, &daily); err != nil {
+		log.Printf("[用量] 加载旧版汇总失败: %v", err)
+		daily = make(map[string]*DailyTotals)
+		return false
+	}
+	if err := storage.SaveData(usageDataName, daily); err != nil {
+		log.Printf("[用量] 迁移旧版汇总失败: %v", err)
+		return true
+	}
+	os.Remove(legacy)
+	return true
+}
+
+// saveTotalsLocked 保存每日汇总
+func saveTotalsLocked() {
+	if err := storage.SaveData(usageDataName, daily); err != nil {
+		log.Printf("[用量] 保存汇总失败: %v", err)
+	}
+}
+
+// pruneLocked 删除超出保留天数的每日汇总和明细文件，UsageRetentionDays 为 0 时永久保留
+func pruneLocked(now time.Time) {
+	if common.UsageRetentionDays <= 0 {
+		return
+	}
+	cutoff := now.AddDate(0, 0, -common.UsageRetentionDays).Format(dateLayout)
+
+	for date := range daily {
+		if date < cutoff {
+			delete(daily, date)
+		}
+	}
+
+	files, err := os.ReadDir(UsageDataDir)
+	if err != nil {
+		return
+	}
+	removed := 0
+	for _, f := range files {
+		date, ok := fileDate(f.Name())
+		if !ok || date >= cutoff {
+			continue
+		}
+		if err := os.Remove(filepath.Join(UsageDataDir, f.Name())); err != nil {
+			log.Printf("[用量] 删除过期明细失败: %v", err)
+			continue
+		}
+		removed++
+	}
+	if removed > 0 {
+		log.Printf("[用量] 已删除 %d 个超过 %d 天的明细文件", removed, common.UsageRetentionDays)
+	}
+}
+
+// fileDate 从明细文件名（YYYY-MM-DD.jsonl）中取出日期
+func fileDate(name string) (string, bool) {
+	if filepath.Ext(name) != ".jsonl" {
+		return "", false
+	}
+	date := strings.TrimSuffix(name, ".jsonl")
+	if _, err := time.Parse(dateLayout, date); err != nil {
+		return "", false
+	}
+	return date, true
+}
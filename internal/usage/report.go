@@ -0,0 +1,136 @@
+package usage
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"QQBot/internal/command"
+)
+
+const defaultTopN = 10 // 用量排行显示人数
+
+func init() {
+	command.Register(&command.Command{
+		Name:        "usage",
+		Aliases:     []string{"今日用量", "用量"},
+		Description: "查看某天的 AI 用量和费用（默认今天）",
+		Permission:  command.PermMaster,
+		Args:        []command.Arg{{Name: "日期", Optional: true}},
+		Run:         runUsage,
+	})
+	command.Register(&command.Command{
+		Name:        "usage-top",
+		Aliases:     []string{"用量排行"},
+		Description: "查看最近几天花费最多的用户（默认今天）",
+		Permission:  command.PermMaster,
+		Args:        []command.Arg{{Name: "天数", Type: command.ArgInt, Optional: true}},
+		Run:         runUsageTop,
+	})
+}
+
+// Day 获取某天的用量汇总副本（不存在时为空）
+func Day(date string) DailyTotals {
+	usageMu.Lock()
+	defer usageMu.Unlock()
+	loadTotalsLocked()
+
+	result := DailyTotals{Users: make(map[int64]*Totals)}
+	if day, ok := daily[date]; ok {
+		result.Totals = day.Totals
+		for userID, t := range day.Users {
+			copied := *t
+			result.Users[userID] = &copied
+		}
+	}
+	return result
+}
+
+// UserSpend 用户在一段时间内的用量
+type UserSpend struct {
+	UserID int64
+	Totals
+}
+
+// TopUsers 统计最近 days 天（含今天）花费最多的 n 个用户
+func TopUsers(days, n int) []UserSpend {
+	merged := make(map[int64]*Totals)
+	now := time.Now()
+	for i := 0; i < days; i++ {
+		for userID, t := range Day(now.AddDate(0, 0, -i).Format(dateLayout)).Users {
+			m, ok := merged[userID]
+			if !ok {
+				m = &Totals{}
+				merged[userID] = m
+			}
+			m.Requests += t.Requests
+			m.PromptTokens += t.PromptTokens
+			m.CompletionTokens += t.CompletionTokens
+			m.CacheHitTokens += t.CacheHitTokens
+			m.LatencyMs += t.LatencyMs
+			m.Cost += t.Cost
+		}
+	}
+
+	list := make([]UserSpend, 0, len(merged))
+	for userID, t := range merged {
+		list = append(list, UserSpend{UserID: userID, Totals: *t})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Cost > list[j].Cost })
+	if len(list) > n {
+		list = list[:n]
+	}
+	return list
+}
+
+func runUsage(ctx *command.Context) error {
+	date := time.Now().Format(dateLayout)
+	if ctx.Has("日期") {
+		t, err := time.Parse(dateLayout, ctx.String("日期"))
+		if err != nil {
+			return command.Usagef("日期格式应为 2006-01-02")
+		}
+		date = t.Format(dateLayout)
+	}
+
+	day := Day(date)
+	if day.Requests == 0 {
+		ctx.Reply(fmt.Sprintf("%s 还没有调用过 AI", date))
+		return nil
+	}
+
+	hitRate := 0.0
+	if day.PromptTokens > 0 {
+		hitRate = float64(day.CacheHitTokens) / float64(day.PromptTokens) * 100
+	}
+	ctx.Reply(fmt.Sprintf("%s 用量：\n请求 %d 次，%d 位用户\n输入 %d token（缓存命中 %.1f%%）\n输出 %d token\n平均耗时 %.1f 秒\n估算费用 ¥%.4f",
+		date, day.Requests, len(day.Users), day.PromptTokens, hitRate, day.CompletionTokens,
+		float64(day.LatencyMs)/float64(day.Requests)/1000, day.Cost))
+	return nil
+}
+
+func runUsageTop(ctx *command.Context) error {
+	days := 1
+	if ctx.Has("天数") {
+		days = ctx.Int("天数")
+		if days <= 0 {
+			return command.Usagef("天数应大于 0")
+		}
+	}
+
+	top := TopUsers(days, defaultTopN)
+	if len(top) == 0 {
+		ctx.Reply("这段时间还没有人调用过 AI")
+		return nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("最近 %d 天花费排行：", days))
+	for i, u := range top {
+		sb.WriteString(fmt.Sprintf("\n%d. %d：%d 次，%d token，¥%.4f",
+			i+1, u.UserID, u.Requests, u.PromptTokens+u.CompletionTokens, u.Cost))
+	}
+	ctx.Reply(sb.String())
+	return nil
+}
@@ -0,0 +1,180 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"QQBot/internal/common"
+)
+
+// Job 一个异步任务（如一次 AI 对话）
+type Job struct {
+	Key       string // 串行队列键：同一键的任务按提交顺序依次执行（如同一私聊用户、同一个群）
+	Supersede string // 非空时，新任务会取消同一 Supersede 的旧任务（排队中或执行中）
+	Run       func(ctx context.Context)
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// queue 单个会话的任务队列
+type queue struct {
+	jobs    []*Job
+	running *Job
+}
+
+var (
+	mu     sync.Mutex
+	queues = make(map[string]*queue)
+	sem    = make(chan struct{}, max(common.AIWorkers, 1)) // 全局并发上限
+	closed bool                                            // Drain 后不再接受新任务
+)
+
+// Submit 提交任务，返回是否被接受（队列满且策略为 drop-newest 时拒绝）
+// ctx 取消时任务也会被取消
+func Submit(ctx context.Context, job Job) bool {
+	job.ctx, job.cancel = context.WithCancel(ctx)
+	j := &job
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if closed {
+		log.Printf("[任务队列] %s: 正在关闭，拒绝新任务", j.Key)
+		j.cancel()
+		return false
+	}
+
+	q, exists := queues[j.Key]
+	if !exists {
+		q = &queue{}
+		queues[j.Key] = q
+	}
+
+	// 取消被新任务取代的旧任务
+	if j.Supersede != "" {
+		kept := q.jobs[:0]
+		for _, old := range q.jobs {
+			if old.Supersede == j.Supersede {
+				old.cancel()
+				log.Printf("[任务队列] %s: 排队中的任务被新任务取代", j.Key)
+				continue
+			}
+			kept = append(kept, old)
+		}
+		q.jobs = kept
+		if q.running != nil && q.running.Supersede == j.Supersede {
+			q.running.cancel()
+			log.Printf("[任务队列] %s: 执行中的任务被新任务取代", j.Key)
+		}
+	}
+
+	// 队列已满时按策略处理
+	if common.AIQueueSize > 0 && len(q.jobs) >= common.AIQueueSize {
+		if common.AIQueueOverflow == "drop-newest" {
+			log.Printf("[任务队列] %s: 队列已满（%d），丢弃新任务", j.Key, len(q.jobs))
+			j.cancel()
+			return false
+		}
+		log.Printf("[任务队列] %s: 队列已满（%d），丢弃最早的排队任务", j.Key, len(q.jobs))
+		q.jobs[0].cancel()
+		q.jobs = q.jobs[1:]
+	}
+
+	q.jobs = append(q.jobs, j)
+	if !exists {
+		go runQueue(j.Key, q)
+	}
+	return true
+}
+
+// Pending 返回排队中和执行中的任务总数
+func Pending() int {
+	mu.Lock()
+	defer mu.Unlock()
+	n := 0
+	for _, q := range queues {
+		n += len(q.jobs)
+		if q.running != nil {
+			n++
+		}
+	}
+	return n
+}
+
+// Drain 停止接受新任务，等待已有任务执行完毕
+// ctx 到期时取消剩余任务（执行中的 AI 请求会被中断），返回被取消的任务数
+func Drain(ctx context.Context) int {
+	mu.Lock()
+	closed = true
+	mu.Unlock()
+
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for Pending() > 0 {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return cancelAll()
+		}
+	}
+	return 0
+}
+
+// cancelAll 取消所有排队中和执行中的任务
+func cancelAll() int {
+	mu.Lock()
+	defer mu.Unlock()
+	n := 0
+	for _, q := range queues {
+		for _, j := range q.jobs {
+			j.cancel()
+			n++
+		}
+		if q.running != nil {
+			q.running.cancel()
+			n++
+		}
+	}
+	return n
+}
+
+// runQueue 依次执行某个会话的任务，队列清空后退出
+func runQueue(key string, q *queue) {
+	for {
+		mu.Lock()
+		if len(q.jobs) == 0 {
+			delete(queues, key)
+			mu.Unlock()
+			return
+		}
+		j := q.jobs[0]
+		q.jobs = q.jobs[1:]
+		q.running = j
+		mu.Unlock()
+
+		run(j)
+
+		mu.Lock()
+		q.running = nil
+		mu.Unlock()
+	}
+}
+
+// run 获取全局并发名额后执行任务，等待期间被取消则直接跳过
+func run(j *Job) {
+	defer j.cancel()
+
+	select {
+	case sem <- struct{}{}:
+	case <-j.ctx.Done():
+		return
+	}
+	defer func() { <-sem }()
+
+	if j.ctx.Err() == nil {
+		j.Run(j.ctx)
+	}
+}